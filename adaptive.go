@@ -0,0 +1,113 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import "time"
+
+// AdaptiveSize bounds and tunes the block size of a MultiplexReader while it
+// runs.
+type AdaptiveSize struct {
+	// MinB and MaxB bound the block size.
+	MinB, MaxB int
+	// Latency is the target time to fill a block.  Blocks filled in less than
+	// half of Latency may grow and blocks that take longer than Latency
+	// shrink.  Zero only limits growth by the other measures.
+	Latency time.Duration
+	// Idle delivers a partially filled block once the source has produced
+	// nothing for Idle, so that slow streams are still delivered promptly.
+	// Zero waits for full blocks.
+	Idle time.Duration
+}
+
+// SetAdaptiveSize lets the block size vary within the bounds of a.  The block
+// size doubles while the source fills blocks quickly in large reads and the
+// sinks keep up.  It halves when a block is delivered partially filled, misses
+// the latency target, or a sink channel is more than half full.
+//
+// SetAdaptiveSize must be called before the first read.
+func (mr *MultiplexReader) SetAdaptiveSize(a AdaptiveSize) {
+	if a.MinB < 1 {
+		a.MinB = 1
+	}
+	if a.MaxB < a.MinB {
+		a.MaxB = a.MinB
+	}
+	mr.mtx.Lock()
+	defer mr.mtx.Unlock()
+	if mr.baseBi > 0 {
+		panic("late start")
+	}
+	mr.adapt = &a
+	mr.blocksizeB = a.clamp(mr.blocksizeB)
+	if mr.async == nil {
+		mr.async = &asyncReader{rdr: mr.rdr}
+	}
+}
+
+// nextAdaptive reads the next block and resizes the block after it.
+func (mr *MultiplexReader) nextAdaptive() ([]byte, error) {
+	brs := make([]byte, mr.blocksizeB)
+	t0 := time.Now()
+	nn, reads, err := mr.async.fill(brs, mr.adapt.Idle, 0)
+	if err == nil {
+		mr.blocksizeB = mr.adapt.resize(mr.blocksizeB, nn, reads, time.Since(t0), mr.backlog())
+	}
+	return brs[:nn], err
+}
+
+// resize returns the size of the block following one of sizeB bytes that
+// was filled with nn bytes from `reads` source reads over d.
+func (a *AdaptiveSize) resize(sizeB, nn, reads int, d time.Duration, backlog float64) int {
+	switch {
+	case nn < sizeB, backlog > 0.5, a.Latency > 0 && d > a.Latency:
+		sizeB /= 2
+	case reads > 0 && nn/reads*4 >= sizeB && (a.Latency == 0 || d < a.Latency/2):
+		sizeB *= 2
+	}
+	return a.clamp(sizeB)
+}
+
+func (a *AdaptiveSize) clamp(sizeB int) int {
+	if sizeB < a.MinB {
+		return a.MinB
+	}
+	if sizeB > a.MaxB {
+		return a.MaxB
+	}
+	return sizeB
+}
+
+// backlog returns how full the fullest sink channel is as a fraction of its
+// capacity.  called with the lock held.
+func (mr *MultiplexReader) backlog() float64 {
+	m := 0.0
+	for c := range mr.cs {
+		if cap(c) == 0 {
+			continue
+		}
+		if f := float64(len(c)) / float64(cap(c)); f > m {
+			m = f
+		}
+	}
+	return m
+}
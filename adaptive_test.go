@@ -0,0 +1,110 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveGrow(t *testing.T) {
+
+	BN := 1 << 20
+
+	src := make([]byte, BN)
+	rand.New(rand.NewSource(time.Now().Unix())).Read(src)
+
+	mr := NewMultiplexReaderWithSize(bytes.NewReader(src), 64)
+	mr.SetAdaptiveSize(AdaptiveSize{MinB: 16, MaxB: 1 << 12})
+	r0 := mr.NewReader()
+
+	buf := &bytes.Buffer{}
+	bs := make([]byte, 1<<16)
+	mx := 0
+	for {
+		n, err := r0.Read(bs)
+		buf.Write(bs[:n])
+		if n > mx {
+			mx = n
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if !bytes.Equal(buf.Bytes(), src) {
+		t.Fatalf("unexpected value")
+	}
+	if mx != 1<<12 {
+		t.Fatalf("unexpected value: %d", mx)
+	}
+
+}
+
+func TestAdaptiveIdle(t *testing.T) {
+
+	pr, pw := io.Pipe()
+	go func() {
+		for i := 0; i < 5; i++ {
+			pw.Write([]byte("abc"))
+			time.Sleep(time.Millisecond * 50)
+		}
+		pw.Close()
+	}()
+
+	mr := NewMultiplexReaderWithSize(pr, 1<<10)
+	mr.SetAdaptiveSize(AdaptiveSize{MinB: 64, MaxB: 1 << 12, Idle: time.Millisecond * 10})
+	r0 := mr.NewReader()
+	r1 := mr.NewReader()
+
+	bs := make([]byte, 1<<10)
+	for i := 0; i < 5; i++ {
+		n, err := r0.Read(bs)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if string(bs[:n]) != "abc" {
+			t.Fatalf("unexpected value: %q", bs[:n])
+		}
+		n, err = r1.Read(bs)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if string(bs[:n]) != "abc" {
+			t.Fatalf("unexpected value: %q", bs[:n])
+		}
+	}
+	if mr.blocksizeB != 64 {
+		t.Fatalf("unexpected value: %d", mr.blocksizeB)
+	}
+	n, err := r0.Read(bs)
+	if n != 0 || err != io.EOF {
+		t.Fatalf("unexpected value: %d %v", n, err)
+	}
+
+}
@@ -0,0 +1,128 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import (
+	"io"
+	"time"
+)
+
+// asyncReader reads a source in the background so that filling a block can
+// stop waiting on a slow source.  a read still outstanding when a fill
+// returns is collected by the next fill; bytes that did not fit are kept in
+// left.
+type asyncReader struct {
+	rdr  io.Reader
+	c    <-chan readResult
+	left []byte
+	err  error
+}
+
+// fill fills bs from the source.  it returns early with the bytes it has once
+// `idle` passes without the source producing any, or once `delay` passes
+// after the first byte.  zero durations disable the timers, in which case
+// the source is read directly.  reads counts the source reads that produced
+// bytes.
+func (a *asyncReader) fill(bs []byte, idle, delay time.Duration) (nn, reads int, err error) {
+	var dt *time.Timer
+	var dc <-chan time.Time
+	defer func() {
+		if dt != nil {
+			dt.Stop()
+		}
+	}()
+	for nn < len(bs) {
+		if len(a.left) > 0 {
+			n := copy(bs[nn:], a.left)
+			a.left = a.left[n:]
+			nn += n
+			continue
+		}
+		if a.err != nil {
+			return nn, reads, a.err
+		}
+		if idle <= 0 && delay <= 0 && a.c == nil {
+			n := 0
+			n, a.err = a.rdr.Read(bs[nn:])
+			nn += n
+			if n > 0 {
+				reads++
+			}
+			continue
+		}
+		if a.c == nil {
+			a.c = goRead(a.rdr, len(bs)-nn)
+		}
+		var it *time.Timer
+		var ic <-chan time.Time
+		if idle > 0 && nn > 0 {
+			it = time.NewTimer(idle)
+			ic = it.C
+		}
+		if delay > 0 && nn > 0 && dt == nil {
+			dt = time.NewTimer(delay)
+			dc = dt.C
+		}
+		timeout := false
+		select {
+		case res := <-a.c:
+			a.c = nil
+			a.left = res.bs
+			a.err = res.err
+			if len(res.bs) > 0 {
+				reads++
+			}
+		case <-ic:
+			timeout = true
+		case <-dc:
+			timeout = true
+		}
+		if it != nil {
+			it.Stop()
+		}
+		if timeout {
+			return nn, reads, nil
+		}
+	}
+	return nn, reads, nil
+}
+
+// read reads once from the source.  bytes left from an earlier fill are
+// returned first and an outstanding read is waited for.
+func (a *asyncReader) read(bs []byte) (int, error) {
+	if len(a.left) == 0 && a.err == nil {
+		if a.c == nil {
+			return a.rdr.Read(bs)
+		}
+		res := <-a.c
+		a.c = nil
+		a.left = res.bs
+		a.err = res.err
+	}
+	n := copy(bs, a.left)
+	a.left = a.left[n:]
+	if len(a.left) == 0 {
+		return n, a.err
+	}
+	return n, nil
+}
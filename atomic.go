@@ -0,0 +1,184 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+)
+
+var ErrNoQuorum = errors.New("quorum not reached")
+
+// AtomicFile is a file destination that is written to a temporary file in the
+// directory of its name and renamed over its name only when committed.  A
+// reader of the name sees either the previous file or the whole replica.
+type AtomicFile struct {
+	f    *os.File
+	name string
+	sync bool
+	// prepared is set once the file is flushed and closed and done once it
+	// is committed or aborted
+	prepared bool
+	done     bool
+}
+
+// CreateAtomic creates an AtomicFile that is renamed to name with permissions
+// perm when committed.  Files are synced to disk before they are renamed, and
+// their directory after.
+func CreateAtomic(name string, perm os.FileMode) (*AtomicFile, error) {
+	dir, base := filepath.Split(name)
+	var f *os.File
+	var err error
+	for i := 0; i < 1<<10; i++ {
+		// perm is applied as by os.OpenFile, less the umask
+		tmp := filepath.Join(dir, "."+base+".tmp"+strconv.FormatUint(uint64(rand.Uint32()), 10))
+		f, err = os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if !os.IsExist(err) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &AtomicFile{
+		f:    f,
+		name: name,
+		sync: true,
+	}, nil
+}
+
+// Name returns the name the file is committed to.
+func (a *AtomicFile) Name() string {
+	return a.name
+}
+
+func (a *AtomicFile) file() *os.File {
+	return a.f
+}
+
+// SetSync sets whether the file and its directory are synced to disk.
+func (a *AtomicFile) SetSync(sync bool) {
+	a.sync = sync
+}
+
+// Write fulfills the io.Writer interface
+func (a *AtomicFile) Write(bs []byte) (int, error) {
+	if a.prepared {
+		return 0, os.ErrClosed
+	}
+	return a.f.Write(bs)
+}
+
+// Prepare syncs the file to disk and closes it, ready to commit.  The file is
+// aborted if it cannot be prepared.
+func (a *AtomicFile) Prepare() error {
+	if a.prepared {
+		return nil
+	}
+	a.prepared = true
+	var err error
+	if a.sync {
+		err = a.f.Sync()
+	}
+	if cerr := a.f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		a.Abort()
+	}
+	return err
+}
+
+// Commit prepares the file and renames it over its name.
+func (a *AtomicFile) Commit() error {
+	if a.done {
+		return os.ErrClosed
+	}
+	if err := a.Prepare(); err != nil {
+		return err
+	}
+	a.done = true
+	if err := os.Rename(a.f.Name(), a.name); err != nil {
+		os.Remove(a.f.Name())
+		return err
+	}
+	if !a.sync {
+		return nil
+	}
+	return syncDir(filepath.Dir(a.name))
+}
+
+// Abort removes the temporary file.  The file at its name is unchanged.
+// Abort after Commit does nothing.
+func (a *AtomicFile) Abort() error {
+	if a.done {
+		return nil
+	}
+	a.done = true
+	if !a.prepared {
+		a.prepared = true
+		a.f.Close()
+	}
+	return os.Remove(a.f.Name())
+}
+
+// syncDir syncs the directory dir so that renames in it are durable.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		// directories cannot be synced
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// CopyFiles copies src to atomic files at each of names, reading src once.
+// The files are committed only if at least quorum of them are written in
+// full, or all of them if quorum is zero or less.  Otherwise all are aborted
+// and the files already at names are left unchanged.  Files that fail are
+// aborted and reported in a *CopyError, along with those aborted for want of
+// a quorum with ErrNoQuorum.
+func CopyFiles(src io.Reader, quorum int, names ...string) (int64, error) {
+	cs := make([]Committer, len(names))
+	errs := make([]error, len(names))
+	for i, name := range names {
+		a, err := CreateAtomic(name, 0666)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		cs[i] = a
+	}
+	return replicate(cs, errs, quorum, func(dsts []io.Writer) (int64, error) {
+		return Copy(src, dsts...)
+	})
+}
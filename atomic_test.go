@@ -0,0 +1,159 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAtomicFile(t *testing.T) {
+
+	dir := t.TempDir()
+	name := filepath.Join(dir, "dst")
+	if err := ioutil.WriteFile(name, []byte("old"), 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	a, err := CreateAtomic(name, 0600)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := io.WriteString(a, SHORT_GREEK); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if bs, _ := ioutil.ReadFile(name); string(bs) != "old" {
+		t.Fatalf("unexpected value")
+	}
+	if err := a.Commit(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if bs, _ := ioutil.ReadFile(name); string(bs) != SHORT_GREEK {
+		t.Fatalf("unexpected value")
+	}
+	if err := a.Abort(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	a, err = CreateAtomic(name, 0600)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	io.WriteString(a, "partial")
+	if err := a.Abort(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if bs, _ := ioutil.ReadFile(name); string(bs) != SHORT_GREEK {
+		t.Fatalf("unexpected value")
+	}
+	if fis, _ := ioutil.ReadDir(dir); len(fis) != 1 {
+		t.Fatalf("unexpected value")
+	}
+
+}
+
+func TestCopyFiles(t *testing.T) {
+
+	dir := t.TempDir()
+	names := []string{
+		filepath.Join(dir, "a"),
+		filepath.Join(dir, "b"),
+		filepath.Join(dir, "c"),
+	}
+	n, err := CopyFiles(strings.NewReader(LONG_GREEK), 0, names...)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if n != int64(len(LONG_GREEK)) {
+		t.Fatalf("unexpected value")
+	}
+	for _, name := range names {
+		if bs, _ := ioutil.ReadFile(name); string(bs) != LONG_GREEK {
+			t.Fatalf("unexpected value")
+		}
+	}
+	if fis, _ := ioutil.ReadDir(dir); len(fis) != len(names) {
+		t.Fatalf("unexpected value")
+	}
+
+}
+
+func TestCopyFilesQuorum(t *testing.T) {
+
+	dir := t.TempDir()
+	names := []string{
+		filepath.Join(dir, "a"),
+		filepath.Join(dir, "missing", "b"),
+		filepath.Join(dir, "c"),
+	}
+	ioutil.WriteFile(names[0], []byte("old"), 0600)
+
+	// all are required
+	_, err := CopyFiles(strings.NewReader(LONG_GREEK), 0, names...)
+	cerr, ok := err.(*CopyError)
+	if !ok {
+		t.Fatalf("unexpected value: %v", err)
+	}
+	if cerr.Errs[0] != ErrNoQuorum || cerr.Errs[2] != ErrNoQuorum || cerr.Errs[1] == nil {
+		t.Fatalf("unexpected value: %v", cerr.Errs)
+	}
+	if bs, _ := ioutil.ReadFile(names[0]); string(bs) != "old" {
+		t.Fatalf("unexpected value")
+	}
+	if _, err := os.Stat(names[2]); !os.IsNotExist(err) {
+		t.Fatalf("unexpected value")
+	}
+
+	// two will do
+	_, err = CopyFiles(strings.NewReader(LONG_GREEK), 2, names...)
+	cerr, ok = err.(*CopyError)
+	if !ok {
+		t.Fatalf("unexpected value: %v", err)
+	}
+	if cerr.Errs[0] != nil || cerr.Errs[2] != nil || cerr.Errs[1] == nil {
+		t.Fatalf("unexpected value: %v", cerr.Errs)
+	}
+	for _, name := range []string{names[0], names[2]} {
+		if bs, _ := ioutil.ReadFile(name); string(bs) != LONG_GREEK {
+			t.Fatalf("unexpected value")
+		}
+	}
+
+	// a failed source commits nothing
+	_, err = CopyFiles(io.MultiReader(strings.NewReader(SHORT_GREEK), &errReader{errors.New("testing")}), 1, names[0], names[2])
+	if err == nil {
+		t.Fatalf("unexpected value")
+	}
+	if bs, _ := ioutil.ReadFile(names[0]); string(bs) != LONG_GREEK {
+		t.Fatalf("unexpected value")
+	}
+	if fis, _ := ioutil.ReadDir(dir); len(fis) != 2 {
+		t.Fatalf("unexpected value")
+	}
+
+}
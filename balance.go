@@ -0,0 +1,189 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import (
+	"bufio"
+	"io"
+)
+
+// Chooser selects the sink that receives data from a MultiplexReader that
+// balances its source across its sinks.  sinks holds every sink in the order
+// it was created, with nil in place of sinks that have been closed.  data is
+// the block, or the record when the source is framed by SetSplit.  The data
+// is discarded if the index returned is out of range or names a nil sink.
+type Chooser func(data []byte, sinks []*Reader) int
+
+// Block is a block of the source along with its position.
+type Block struct {
+	// Seq numbers the blocks, or records, distributed from the source in
+	// order starting at zero.
+	Seq int64
+	// Offset is the offset of Data in the source.
+	Offset int64
+	Data   []byte
+	// Sum is the checksum of Data set by SetBlockChecksums, or nil if it is
+	// not set or Data is only part of a block.
+	Sum []byte
+}
+
+// SetChooser delivers each block, or each record when the source is framed by
+// SetSplit, to the one sink selected by choose instead of to every sink.
+// Errors from the source, including io.EOF, still reach every sink.  Sinks
+// receive the sequence number and offset of each block with ReadBlock so
+// that their outputs can be reassembled in order.  A range sink is only
+// offered the blocks that overlap its range and receives the part within it.
+//
+// SetChooser must be called before the first read.
+func (mr *MultiplexReader) SetChooser(choose Chooser) {
+	mr.mtx.Lock()
+	defer mr.mtx.Unlock()
+	if mr.baseBi > 0 {
+		panic("late start")
+	}
+	mr.choose = choose
+}
+
+// RoundRobin returns a Chooser that cycles through the open sinks.
+func RoundRobin() Chooser {
+	i := 0
+	return func(data []byte, sinks []*Reader) int {
+		for j := 0; j < len(sinks); j++ {
+			k := (i + j) % len(sinks)
+			if sinks[k] != nil {
+				i = k + 1
+				return k
+			}
+		}
+		return -1
+	}
+}
+
+// LeastLoaded returns a Chooser that selects the open sink with the fewest
+// blocks queued.
+func LeastLoaded() Chooser {
+	return func(data []byte, sinks []*Reader) int {
+		m := -1
+		for i, r := range sinks {
+			if r != nil && (m < 0 || r.Len() < sinks[m].Len()) {
+				m = i
+			}
+		}
+		return m
+	}
+}
+
+// balance delivers each block, or record, to one chosen sink.  called with
+// the lock held.
+func (mr *MultiplexReader) balance(bs []byte, err error, self *Reader) {
+	open := make([]*Reader, len(mr.sinks))
+	for i, r := range mr.sinks {
+		if _, ok := mr.cs[r.c]; ok {
+			open[i] = r
+		}
+	}
+	sinks := make([]*Reader, len(open))
+	k := 0
+	for k < len(bs) {
+		unit, data := bs[k:], bs[k:]
+		if mr.split != nil {
+			// blocks end on record boundaries so each is split as if at
+			// the end of the stream
+			adv, tok, serr := mr.split(bs[k:], true)
+			if (serr == nil || serr == bufio.ErrFinalToken) && adv > 0 && adv <= len(bs)-k {
+				unit = bs[k : k+adv]
+				data = unit
+				if tok != nil {
+					data = tok
+				}
+			}
+		}
+		lo := mr.baseBi + int64(k)
+		hi := lo + int64(len(unit))
+		sum := mr.checksum(lo, unit)
+		// range sinks are only offered the units that overlap their range
+		for i, r := range open {
+			sinks[i] = nil
+			if r != nil && r.overlaps(lo, hi) {
+				sinks[i] = r
+			}
+		}
+		i := mr.choose(data, sinks)
+		if i >= 0 && i < len(sinks) && sinks[i] != nil {
+			ent, _ := sinks[i].clip(lo, unit, nil)
+			ent.seq = mr.seq
+			if len(ent.bs) == len(unit) {
+				ent.sum = sum
+			}
+			send(sinks[i], ent, self)
+			if ent.err != nil {
+				// the range of the chosen sink ended with this unit
+				delete(mr.cs, sinks[i].c)
+				open[i] = nil
+			}
+		}
+		for j, r := range open {
+			if r != nil && r.endBi >= 0 && hi >= r.endBi {
+				// range complete.  stop offering units to this sink
+				send(r, entry{
+					i:   r.endBi,
+					seq: mr.seq,
+					bs:  []byte{},
+					err: io.EOF,
+				}, self)
+				delete(mr.cs, r.c)
+				open[j] = nil
+			}
+		}
+		mr.seq++
+		k += len(unit)
+	}
+	if err == nil {
+		return
+	}
+	mr.endChecksums(err)
+	for _, r := range mr.cs {
+		send(r, entry{
+			i:   mr.baseBi + int64(len(bs)),
+			seq: mr.seq,
+			bs:  []byte{},
+			err: err,
+		}, self)
+	}
+}
+
+// ReadBlock returns the rest of the current block, or else the next block,
+// with its sequence number and offset in the source.
+func (r *Reader) ReadBlock() (Block, error) {
+	var b Block
+	_, err := r.read(func() (int, error) {
+		b = Block{
+			Seq:    r.seq,
+			Offset: r.baseBi,
+			Data:   make([]byte, len(r.buf)),
+			Sum:    r.sum,
+		}
+		return copy(b.Data, r.buf), nil
+	})
+	return b, err
+}
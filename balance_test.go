@@ -0,0 +1,169 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collect reads the blocks of each sink concurrently
+func collect(t *testing.T, rss []*Reader) [][]Block {
+	bss := make([][]Block, len(rss))
+	wg := sync.WaitGroup{}
+	for i := range rss {
+		wg.Add(1)
+		go func(j int) {
+			defer wg.Done()
+			for {
+				b, err := rss[j].ReadBlock()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Errorf("err: %v", err)
+					return
+				}
+				bss[j] = append(bss[j], b)
+			}
+		}(i)
+	}
+	wg.Wait()
+	return bss
+}
+
+func TestBalanceRoundRobin(t *testing.T) {
+
+	BN := 1<<16 + 3
+
+	src := make([]byte, BN)
+	rand.New(rand.NewSource(time.Now().Unix())).Read(src)
+
+	mr := NewMultiplexReaderWithSize(bytes.NewReader(src), 1<<10)
+	mr.SetChooser(RoundRobin())
+
+	N := 4
+	rss := make([]*Reader, N)
+	for i := 0; i < N; i++ {
+		rss[i] = mr.NewReaderWithLength(2)
+	}
+
+	bss := collect(t, rss)
+	all := []Block{}
+	for i := 0; i < N; i++ {
+		// every fourth block
+		for _, b := range bss[i] {
+			if int(b.Seq)%N != i {
+				t.Fatalf("unexpected value")
+			}
+		}
+		all = append(all, bss[i]...)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Seq < all[j].Seq
+	})
+	buf := &bytes.Buffer{}
+	for _, b := range all {
+		if b.Offset != int64(buf.Len()) {
+			t.Fatalf("unexpected value")
+		}
+		buf.Write(b.Data)
+	}
+	if !bytes.Equal(buf.Bytes(), src) {
+		t.Fatalf("unexpected value")
+	}
+
+}
+
+func TestBalanceRange(t *testing.T) {
+
+	src := "0123456789abcdefghij"
+
+	mr := NewMultiplexReaderWithSize(strings.NewReader(src), 4)
+	mr.SetChooser(RoundRobin())
+
+	r0 := mr.NewRangeReader(10, 5)
+	bss := collect(t, []*Reader{r0})
+	buf := &bytes.Buffer{}
+	for _, b := range bss[0] {
+		buf.Write(b.Data)
+	}
+	if buf.String() != "abcde" {
+		t.Fatalf("unexpected value: %q", buf.String())
+	}
+
+	// a range sink shares only the blocks of its range
+	mr = NewMultiplexReaderWithSize(strings.NewReader(src), 4)
+	mr.SetChooser(RoundRobin())
+	rss := []*Reader{mr.NewReader(), mr.NewRangeReader(4, 6)}
+	bss = collect(t, rss)
+	if len(bss[1]) != 1 || string(bss[1][0].Data) != "4567" || bss[1][0].Offset != 4 {
+		t.Fatalf("unexpected value: %v", bss[1])
+	}
+	buf.Reset()
+	for _, b := range bss[0] {
+		buf.Write(b.Data)
+	}
+	if buf.String() != "012389abcdefghij" {
+		t.Fatalf("unexpected value: %q", buf.String())
+	}
+
+}
+
+func TestBalanceRecords(t *testing.T) {
+
+	mr := NewMultiplexReaderWithSize(strings.NewReader(LONG_GREEK), 64)
+	mr.SetSplit(bufio.ScanWords, 0)
+	mr.SetChooser(LeastLoaded())
+
+	N := 3
+	rss := make([]*Reader, N)
+	for i := 0; i < N; i++ {
+		rss[i] = mr.NewReader()
+	}
+
+	bss := collect(t, rss)
+	words := map[int64]string{}
+	for i := 0; i < N; i++ {
+		for _, b := range bss[i] {
+			words[b.Seq] = strings.TrimSpace(string(b.Data))
+		}
+	}
+	ws := strings.Fields(LONG_GREEK)
+	if len(words) != len(ws) {
+		t.Fatalf("unexpected value: %d", len(words))
+	}
+	for i, w := range ws {
+		if words[int64(i)] != w {
+			t.Fatalf("unexpected value: %q", words[int64(i)])
+		}
+	}
+
+}
@@ -0,0 +1,116 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import (
+	"io"
+	"os"
+)
+
+// Committer is a destination whose writes take effect only when committed.
+// Prepare readies a destination written in full to be committed, such that
+// Commit is unlikely to fail.  Abort discards the writes and is called
+// instead of, or after a failed, Prepare or Commit.
+type Committer interface {
+	io.Writer
+	Prepare() error
+	Commit() error
+	Abort() error
+}
+
+// Replicate copies the source to each of cs, with one sink each, and returns
+// the number of bytes copied.  After EOF every destination is prepared, and
+// all are committed only if all are written in full and prepared.  Otherwise
+// all are aborted, and the errors are reported in a *CopyError along with
+// ErrNoQuorum for the destinations aborted only because others failed.
+//
+// Replicate must be called before the first read.
+func (mr *MultiplexReader) Replicate(cs ...Committer) (int64, error) {
+	return replicate(cs, make([]error, len(cs)), 0, mr.copyTo)
+}
+
+// replicate copies to cs with copyTo and commits them if at least quorum, or
+// all if quorum is zero or less, are written and prepared.  errs holds the
+// errors of destinations that are nil in cs.
+func replicate(cs []Committer, errs []error, quorum int, copyTo func(dsts []io.Writer) (int64, error)) (int64, error) {
+	dsts := []io.Writer{}
+	idx := []int{}
+	for i, c := range cs {
+		if c == nil {
+			continue
+		}
+		var w io.Writer = c
+		if a, ok := c.(interface{ file() *os.File }); ok {
+			// write the file directly so the fast paths for files apply
+			w = a.file()
+		}
+		dsts = append(dsts, w)
+		idx = append(idx, i)
+	}
+	var nn int64
+	if len(dsts) > 0 {
+		var err error
+		nn, err = copyTo(dsts)
+		if cerr, ok := err.(*CopyError); ok {
+			for j, i := range idx {
+				errs[i] = cerr.Errs[j]
+			}
+		} else if err != nil {
+			for _, i := range idx {
+				errs[i] = err
+			}
+		}
+	}
+	ok := 0
+	for i, c := range cs {
+		if c != nil && errs[i] == nil {
+			errs[i] = c.Prepare()
+		}
+		if errs[i] == nil {
+			ok++
+		}
+	}
+	if quorum <= 0 {
+		quorum = len(cs)
+	}
+	for i, c := range cs {
+		switch {
+		case c == nil:
+		case errs[i] != nil:
+			c.Abort()
+		case ok < quorum:
+			c.Abort()
+			errs[i] = ErrNoQuorum
+		default:
+			if errs[i] = c.Commit(); errs[i] != nil {
+				c.Abort()
+			}
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nn, &CopyError{Errs: errs}
+		}
+	}
+	return nn, nil
+}
@@ -0,0 +1,111 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// memCommitter is an in memory Committer
+type memCommitter struct {
+	bytes.Buffer
+	prepareErr error
+	state      string
+}
+
+func (c *memCommitter) Prepare() error {
+	c.state = "prepared"
+	return c.prepareErr
+}
+
+func (c *memCommitter) Commit() error {
+	c.state = "committed"
+	return nil
+}
+
+func (c *memCommitter) Abort() error {
+	c.state = "aborted"
+	return nil
+}
+
+func TestReplicate(t *testing.T) {
+
+	name := filepath.Join(t.TempDir(), "dst")
+	a, err := CreateAtomic(name, 0600)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	c0 := &memCommitter{}
+	c1 := &memCommitter{}
+
+	mr := NewMultiplexReaderWithSize(strings.NewReader(LONG_GREEK), 100)
+	n, err := mr.Replicate(c0, a, c1)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if n != int64(len(LONG_GREEK)) {
+		t.Fatalf("unexpected value")
+	}
+	for _, c := range []*memCommitter{c0, c1} {
+		if c.state != "committed" || c.String() != LONG_GREEK {
+			t.Fatalf("unexpected value")
+		}
+	}
+	if bs, _ := ioutil.ReadFile(name); string(bs) != LONG_GREEK {
+		t.Fatalf("unexpected value")
+	}
+
+}
+
+func TestReplicateAbort(t *testing.T) {
+
+	name := filepath.Join(t.TempDir(), "dst")
+	a, err := CreateAtomic(name, 0600)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	c0 := &memCommitter{}
+	c1 := &memCommitter{prepareErr: errors.New("testing")}
+
+	mr := NewMultiplexReaderWithSize(strings.NewReader(LONG_GREEK), 100)
+	_, err = mr.Replicate(c0, a, c1)
+	cerr, ok := err.(*CopyError)
+	if !ok {
+		t.Fatalf("unexpected value: %v", err)
+	}
+	if cerr.Errs[0] != ErrNoQuorum || cerr.Errs[1] != ErrNoQuorum || cerr.Errs[2] != c1.prepareErr {
+		t.Fatalf("unexpected value: %v", cerr.Errs)
+	}
+	if c0.state != "aborted" || c1.state != "aborted" {
+		t.Fatalf("unexpected value")
+	}
+	if fis, _ := ioutil.ReadDir(filepath.Dir(name)); len(fis) != 0 {
+		t.Fatalf("unexpected value")
+	}
+
+}
@@ -0,0 +1,112 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// CopyError reports the destinations of a copy that failed.
+type CopyError struct {
+	// Errs holds the error of each destination in order, nil for those that
+	// succeeded.
+	Errs []error
+}
+
+func (e *CopyError) Error() string {
+	n := 0
+	for _, err := range e.Errs {
+		if err != nil {
+			n++
+		}
+	}
+	return fmt.Sprintf("%d of %d destinations failed: %v", n, len(e.Errs), e.Unwrap())
+}
+
+// Unwrap returns the error of the first destination that failed.
+func (e *CopyError) Unwrap() error {
+	for _, err := range e.Errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Copy copies src to each of dsts, reading src once.  It returns the number
+// of bytes copied to the destinations that succeeded.  A destination that
+// fails is dropped without holding back the others, and the failures are
+// returned as a *CopyError.
+//
+// On linux, when src is a pipe and every destination is a pipe, socket or
+// regular file, the copy is made with tee(2) and splice(2) instead, and the
+// data is not copied through user space.  The destinations then advance
+// together, each buffered by a pipe.
+func Copy(src io.Reader, dsts ...io.Writer) (int64, error) {
+	if ok, nn, err := spliceCopy(src, dsts); ok {
+		return nn, err
+	}
+	return NewMultiplexReader(src).copyTo(dsts)
+}
+
+// copyTo copies the source to dsts with one sink each.
+func (mr *MultiplexReader) copyTo(dsts []io.Writer) (int64, error) {
+	rs := make([]*Reader, len(dsts))
+	for i := range dsts {
+		rs[i] = mr.NewReader()
+	}
+	ns, errs := copyReaders(rs, dsts)
+	var nn int64
+	failed := false
+	for i, err := range errs {
+		if err != nil {
+			failed = true
+		} else if ns[i] > nn {
+			nn = ns[i]
+		}
+	}
+	if failed {
+		return nn, &CopyError{Errs: errs}
+	}
+	return nn, nil
+}
+
+// copyReaders writes each of the sinks rs to the matching destination in
+// dsts concurrently, closing each sink once it is written.
+func copyReaders(rs []*Reader, dsts []io.Writer) ([]int64, []error) {
+	ns := make([]int64, len(dsts))
+	errs := make([]error, len(dsts))
+	wg := sync.WaitGroup{}
+	for i := range dsts {
+		wg.Add(1)
+		go func(j int) {
+			defer wg.Done()
+			ns[j], errs[j] = rs[j].WriteTo(dsts[j])
+			rs[j].CloseWithError(errs[j])
+		}(i)
+	}
+	wg.Wait()
+	return ns, errs
+}
@@ -0,0 +1,274 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+//go:build linux
+// +build linux
+
+package multio
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+const (
+	_SPLICE_F_MOVE = 1
+	_F_GETFL       = 3
+)
+
+// spliceCopy copies a pipe to pipes, sockets and regular files without
+// copying through user space.  Each destination has a pipe of its own that
+// tee(2) duplicates the source into, and the contents of each pipe are moved to
+// its destination with splice(2) before more of the source is taken.  handled
+// is false, and nothing is read, unless src and every destination support
+// this.
+func spliceCopy(src io.Reader, dsts []io.Writer) (handled bool, nn int64, err error) {
+	sf, ok := src.(*os.File)
+	if !ok || len(dsts) == 0 {
+		return false, 0, nil
+	}
+	sc, err := sf.SyscallConn()
+	if err != nil || !spliceable(sc, true) {
+		return false, 0, nil
+	}
+	rcs := make([]syscall.RawConn, len(dsts))
+	for i, w := range dsts {
+		c, ok := w.(syscall.Conn)
+		if !ok {
+			return false, 0, nil
+		}
+		rc, err := c.SyscallConn()
+		if err != nil || !spliceable(rc, false) {
+			return false, 0, nil
+		}
+		rcs[i] = rc
+	}
+
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return false, 0, nil
+	}
+	defer null.Close()
+	ps := make([][2]int, len(dsts))
+	defer func() {
+		for _, p := range ps {
+			if p[0] > 0 {
+				syscall.Close(p[0])
+				syscall.Close(p[1])
+			}
+		}
+	}()
+	for i := range ps {
+		p := make([]int, 2)
+		if err := syscall.Pipe2(p, syscall.O_CLOEXEC); err != nil {
+			return false, 0, nil
+		}
+		ps[i] = [2]int{p[0], p[1]}
+	}
+
+	s := &splicer{
+		src:  sc,
+		null: int(null.Fd()),
+		rcs:  rcs,
+		ps:   ps,
+		got:  make([]int64, len(dsts)),
+		held: make([]int64, len(dsts)),
+		errs: make([]error, len(dsts)),
+	}
+	err = s.run()
+	if err == nil {
+		for _, derr := range s.errs {
+			if derr != nil {
+				err = &CopyError{Errs: s.errs}
+				break
+			}
+		}
+	}
+	for i, derr := range s.errs {
+		if derr == nil && s.got[i] > nn {
+			nn = s.got[i]
+		}
+	}
+	return true, nn, err
+}
+
+// spliceable reports whether fd is a pipe or, for destinations, a socket or a
+// regular file not opened for appending.
+func spliceable(rc syscall.RawConn, src bool) bool {
+	ok := false
+	rc.Control(func(fd uintptr) {
+		var st syscall.Stat_t
+		if syscall.Fstat(int(fd), &st) != nil {
+			return
+		}
+		switch st.Mode & syscall.S_IFMT {
+		case syscall.S_IFIFO:
+			ok = true
+		case syscall.S_IFSOCK, syscall.S_IFREG:
+			if src {
+				return
+			}
+			fl, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, _F_GETFL, 0)
+			ok = errno == 0 && fl&syscall.O_APPEND == 0
+		}
+	})
+	return ok
+}
+
+type splicer struct {
+	src  syscall.RawConn
+	null int
+	rcs  []syscall.RawConn
+	ps   [][2]int
+	// got counts the bytes of the source duplicated for each destination
+	got []int64
+	// held counts the bytes in the pipe of each destination
+	held []int64
+	// cons counts the bytes taken from the source
+	cons int64
+	errs []error
+}
+
+// run copies until the source ends or every destination fails.  Errors
+// reading the source are returned and errors of destinations are recorded.
+func (s *splicer) run() error {
+	for {
+		active := false
+		for _, err := range s.errs {
+			if err == nil {
+				active = true
+			}
+		}
+		if !active {
+			return nil
+		}
+		// duplicate the source into the pipe of each destination that has
+		// sent all it was given.  all pipes are empty here.
+		eof := false
+		target := int64(-1)
+		for i, p := range s.ps {
+			if s.errs[i] != nil {
+				continue
+			}
+			if s.got[i] == s.cons {
+				k, err := s.tee(p[1])
+				if err != nil {
+					return err
+				}
+				if k == 0 {
+					eof = true
+				}
+				s.got[i] += k
+				s.held[i] = k
+			}
+			if target < 0 || s.got[i] < target {
+				target = s.got[i]
+			}
+		}
+		if eof {
+			return nil
+		}
+		// take from the source what every destination has been given
+		for s.cons < target {
+			k, err := s.discard(target - s.cons)
+			if err != nil {
+				return err
+			}
+			s.cons += k
+		}
+		for i := range s.ps {
+			if s.errs[i] == nil {
+				s.errs[i] = s.drain(i)
+			}
+		}
+	}
+}
+
+// tee duplicates what the source holds into the pipe wfd, waiting for the
+// source to be readable.
+func (s *splicer) tee(wfd int) (n int64, err error) {
+	rerr := s.src.Read(func(fd uintptr) bool {
+		n, err = tee(int(fd), wfd, 1<<20)
+		return err != syscall.EAGAIN
+	})
+	if err == nil {
+		err = rerr
+	}
+	return n, err
+}
+
+// discard removes up to l bytes from the source.
+func (s *splicer) discard(l int64) (n int64, err error) {
+	rerr := s.src.Read(func(fd uintptr) bool {
+		n, err = splice(int(fd), s.null, int(l))
+		return err != syscall.EAGAIN
+	})
+	if err == nil {
+		err = rerr
+	}
+	if err == nil && n == 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// drain moves the contents of the pipe of destination i to it.
+func (s *splicer) drain(i int) error {
+	p := s.ps[i]
+	for s.held[i] > 0 {
+		var n int64
+		var err error
+		werr := s.rcs[i].Write(func(fd uintptr) bool {
+			n, err = splice(p[0], int(fd), int(s.held[i]))
+			return err != syscall.EAGAIN
+		})
+		if err == nil {
+			err = werr
+		}
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return io.ErrShortWrite
+		}
+		s.held[i] -= n
+	}
+	return nil
+}
+
+// tee duplicates up to l bytes from rfd to wfd.  syscall.Tee joins the
+// result with a second register that is not part of it on 32-bit platforms.
+func tee(rfd, wfd, l int) (int64, error) {
+	n, _, e := syscall.Syscall6(syscall.SYS_TEE, uintptr(rfd), uintptr(wfd), uintptr(l), 0, 0, 0)
+	if e != 0 {
+		return 0, e
+	}
+	return int64(n), nil
+}
+
+// splice moves up to l bytes from rfd to wfd.  syscall.Splice returns an int
+// count on 32-bit platforms.
+func splice(rfd, wfd, l int) (int64, error) {
+	n, err := syscall.Splice(rfd, nil, wfd, nil, l, _SPLICE_F_MOVE)
+	return int64(n), err
+}
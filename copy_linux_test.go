@@ -0,0 +1,169 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+//go:build linux
+// +build linux
+
+package multio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+func TestSpliceCopy(t *testing.T) {
+
+	src := make([]byte, 1<<22)
+	rand.New(rand.NewSource(1)).Read(src)
+
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer pr.Close()
+	go func() {
+		// write in pieces so the source runs dry
+		for i := 0; i < len(src); i += 1000 {
+			j := i + 1000
+			if j > len(src) {
+				j = len(src)
+			}
+			pw.Write(src[i:j])
+		}
+		pw.Close()
+	}()
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "dst"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+
+	dpr, dpw, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer ln.Close()
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	peer, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer peer.Close()
+
+	wg := sync.WaitGroup{}
+	var got1, got2 []byte
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		got1, _ = ioutil.ReadAll(dpr)
+	}()
+	go func() {
+		defer wg.Done()
+		got2, _ = ioutil.ReadAll(peer)
+	}()
+
+	handled, n, err := spliceCopy(pr, []io.Writer{f, dpw, conn})
+	dpw.Close()
+	conn.Close()
+	wg.Wait()
+	if !handled {
+		t.Fatalf("unexpected value")
+	}
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if n != int64(len(src)) {
+		t.Fatalf("unexpected value: %d", n)
+	}
+	got0, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, got := range [][]byte{got0, got1, got2} {
+		if !bytes.Equal(got, src) {
+			t.Fatalf("unexpected value")
+		}
+	}
+
+}
+
+func TestSpliceCopyError(t *testing.T) {
+
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer pr.Close()
+	go func() {
+		pw.Write([]byte(LONG_GREEK))
+		pw.Close()
+	}()
+
+	// a destination pipe without a reader fails alone
+	dpr, dpw, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	dpr.Close()
+	defer dpw.Close()
+	f, err := os.Create(filepath.Join(t.TempDir(), "dst"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+
+	n, err := Copy(pr, dpw, f)
+	var cerr *CopyError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("unexpected value: %v", err)
+	}
+	if !errors.Is(cerr.Errs[0], syscall.EPIPE) || cerr.Errs[1] != nil {
+		t.Fatalf("unexpected value: %v", cerr.Errs)
+	}
+	if n != int64(len(LONG_GREEK)) {
+		t.Fatalf("unexpected value")
+	}
+
+	// other writers are not handled
+	handled, _, _ := spliceCopy(pr, []io.Writer{f, &bytes.Buffer{}})
+	if handled {
+		t.Fatalf("unexpected value")
+	}
+
+}
@@ -0,0 +1,86 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+type failWriter struct {
+	n   int
+	err error
+}
+
+func (w *failWriter) Write(bs []byte) (int, error) {
+	if len(bs) > w.n {
+		n := w.n
+		w.n = 0
+		return n, w.err
+	}
+	w.n -= len(bs)
+	return len(bs), nil
+}
+
+func TestCopy(t *testing.T) {
+
+	bufs := []*bytes.Buffer{{}, {}, {}}
+	n, err := Copy(strings.NewReader(LONG_GREEK), bufs[0], bufs[1], bufs[2])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if n != int64(len(LONG_GREEK)) {
+		t.Fatalf("unexpected value")
+	}
+	for _, buf := range bufs {
+		if buf.String() != LONG_GREEK {
+			t.Fatalf("unexpected value")
+		}
+	}
+
+}
+
+func TestCopyError(t *testing.T) {
+
+	buf0 := &bytes.Buffer{}
+	buf1 := &bytes.Buffer{}
+	mr := NewMultiplexReaderWithSize(strings.NewReader(LONG_GREEK), 10)
+	n, err := mr.copyTo([]io.Writer{buf0, &failWriter{100, errors.New("testing")}, buf1})
+	cerr, ok := err.(*CopyError)
+	if !ok {
+		t.Fatalf("unexpected value: %v", err)
+	}
+	if cerr.Errs[0] != nil || cerr.Errs[2] != nil || cerr.Errs[1] == nil {
+		t.Fatalf("unexpected value")
+	}
+	if errors.Unwrap(err).Error() != "testing" {
+		t.Fatalf("unexpected value")
+	}
+	if n != int64(len(LONG_GREEK)) || buf0.String() != LONG_GREEK || buf1.String() != LONG_GREEK {
+		t.Fatalf("unexpected value")
+	}
+
+}
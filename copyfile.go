@@ -0,0 +1,175 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+)
+
+const (
+	modeBits = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+)
+
+// CopyOptions configures the copying of files.
+type CopyOptions struct {
+	// Sync syncs each destination file and its directory to disk before it
+	// is reported copied.
+	Sync bool
+	// Verify reads back each destination file once written and compares
+	// it with hashes of the blocks of the source taken as it was copied.
+	// A destination that does not match is not committed and is reported
+	// with a *VerifyError.  With Sync, the file is read from the device
+	// where the platform allows.
+	Verify bool
+}
+
+// CopyFile copies the regular file src to each of dsts, reading src once and
+// writing the destinations concurrently, and syncs them to disk.  See
+// CopyFileWithOptions.
+func CopyFile(src string, dsts ...string) (int64, error) {
+	return CopyFileWithOptions(src, CopyOptions{Sync: true}, dsts...)
+}
+
+// CopyFileWithOptions copies the regular file src to each of dsts, reading src
+// once and writing the destinations concurrently.  Each destination keeps the
+// mode, modification time and, on linux, the extended attributes of src, and
+// its owner where permitted.  Destinations that cannot keep the owner of src
+// do not keep its setuid and setgid bits.  Destinations are written to atomic
+// files and each replaces the file at its name only once written in full.
+// Those that fail are reported in a *CopyError.
+func CopyFileWithOptions(src string, opts CopyOptions, dsts ...string) (int64, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return copyFile(src, f, opts, dsts)
+}
+
+// copyFile copies the open file f, opened at path, to dsts.  Files other than
+// an *os.File are read without mapping and their extended attributes are not
+// copied.
+func copyFile(path string, f fs.File, opts CopyOptions, dsts []string) (int64, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if !fi.Mode().IsRegular() {
+		return 0, &os.PathError{Op: "copy", Path: path, Err: errors.New("not a regular file")}
+	}
+	src := ""
+	if of, ok := f.(*os.File); ok {
+		src = of.Name()
+	}
+	cs := make([]Committer, len(dsts))
+	errs := make([]error, len(dsts))
+	ms := []*metaFile{}
+	for i, dst := range dsts {
+		a, err := CreateAtomic(dst, fi.Mode().Perm())
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		a.SetSync(opts.Sync)
+		m := &metaFile{
+			AtomicFile: a,
+			src:        src,
+			fi:         fi,
+			verify:     opts.Verify,
+		}
+		cs[i] = m
+		ms = append(ms, m)
+	}
+	copyTo := func(ws []io.Writer) (int64, error) {
+		// the source is only mapped once there are sinks to release it
+		var mr *MultiplexReader
+		if of, ok := f.(*os.File); ok {
+			mr = NewMappedMultiplexReader(of)
+		} else {
+			mr = NewMultiplexReader(f)
+		}
+		if opts.Verify {
+			mr.SetBlockChecksums(DigestSHA256)
+		}
+		for _, m := range ms {
+			m.mr = mr
+		}
+		return mr.copyTo(ws)
+	}
+	// each destination is committed on its own
+	return replicate(cs, errs, 1, copyTo)
+}
+
+// metaFile is an AtomicFile that takes on the metadata of its source when
+// prepared.
+type metaFile struct {
+	*AtomicFile
+	src    string
+	fi     os.FileInfo
+	verify bool
+	mr     *MultiplexReader
+}
+
+func (m *metaFile) Prepare() error {
+	// the mode follows the owner, which may clear setuid and setgid.  as
+	// with cp -p, a copy that is not owned as its source does not keep them
+	owned, err := copyMeta(m.src, m.fi, m.f)
+	if err == nil {
+		mode := m.fi.Mode() & modeBits
+		if !owned {
+			mode &^= os.ModeSetuid | os.ModeSetgid
+		}
+		err = m.f.Chmod(mode)
+	}
+	if err != nil {
+		m.Abort()
+		return err
+	}
+	if err := m.AtomicFile.Prepare(); err != nil {
+		return err
+	}
+	if m.verify {
+		if err := m.readBack(); err != nil {
+			m.Abort()
+			return err
+		}
+	}
+	// the file is closed so that no write follows the times
+	if err := os.Chtimes(m.f.Name(), accessTime(m.fi), m.fi.ModTime()); err != nil {
+		m.Abort()
+		return err
+	}
+	return nil
+}
+
+// readBack compares the prepared file with the source.
+func (m *metaFile) readBack() error {
+	t, err := m.mr.MerkleTree()
+	if err != nil {
+		return err
+	}
+	return verifyFile(t, m.f.Name(), m.name)
+}
@@ -0,0 +1,106 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCopyFile(t *testing.T) {
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := ioutil.WriteFile(src, []byte(LONG_GREEK), 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	os.Chmod(src, 0751)
+	mtime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	os.Chtimes(src, mtime, mtime)
+
+	dsts := []string{
+		filepath.Join(dir, "a"),
+		filepath.Join(dir, "missing", "b"),
+		filepath.Join(dir, "c"),
+	}
+	n, err := CopyFile(src, dsts...)
+	cerr, ok := err.(*CopyError)
+	if !ok {
+		t.Fatalf("unexpected value: %v", err)
+	}
+	if cerr.Errs[0] != nil || cerr.Errs[1] == nil || cerr.Errs[2] != nil {
+		t.Fatalf("unexpected value: %v", cerr.Errs)
+	}
+	if n != int64(len(LONG_GREEK)) {
+		t.Fatalf("unexpected value")
+	}
+
+	for _, dst := range []string{dsts[0], dsts[2]} {
+		bs, err := ioutil.ReadFile(dst)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if string(bs) != LONG_GREEK {
+			t.Fatalf("unexpected value")
+		}
+		fi, err := os.Stat(dst)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if fi.Mode() != 0751 || !fi.ModTime().Equal(mtime) {
+			t.Fatalf("unexpected value: %v %v", fi.Mode(), fi.ModTime())
+		}
+	}
+
+	_, err = CopyFileWithOptions(dir, CopyOptions{}, dsts[0])
+	if perr, ok := err.(*os.PathError); !ok || perr.Path != dir {
+		t.Fatalf("unexpected value: %v", err)
+	}
+
+}
+
+func TestCopyFileUnmapped(t *testing.T) {
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := ioutil.WriteFile(src, []byte(LONG_GREEK), 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// no destination opens so the source is never mapped
+	for i := 0; i < 5; i++ {
+		if _, err := CopyFile(src, filepath.Join(dir, "missing", "dst")); err == nil {
+			t.Fatalf("unexpected value")
+		}
+	}
+	if _, err := CopyFile(src, filepath.Join(dir, "dst")); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if n := mappings(src); n > 0 {
+		t.Fatalf("unexpected value: %d", n)
+	}
+
+}
@@ -0,0 +1,221 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"hash/crc64"
+	"io"
+)
+
+// Digest identifies a hash function computed over the source.
+type Digest int
+
+const (
+	DigestMD5 Digest = iota + 1
+	DigestSHA1
+	DigestSHA256
+	// DigestCRC32C is CRC-32 with the Castagnoli polynomial.
+	DigestCRC32C
+	// DigestCRC64 is CRC-64 with the ISO polynomial.
+	DigestCRC64
+)
+
+var ErrDigestUnavailable = errors.New("digest unavailable")
+
+// New returns a new hash.Hash computing d.
+func (d Digest) New() hash.Hash {
+	switch d {
+	case DigestMD5:
+		return md5.New()
+	case DigestSHA1:
+		return sha1.New()
+	case DigestSHA256:
+		return sha256.New()
+	case DigestCRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case DigestCRC64:
+		return crc64.New(crc64.MakeTable(crc64.ISO))
+	}
+	panic("unknown digest")
+}
+
+// Sum returns the digest d of bs.
+func (d Digest) Sum(bs []byte) []byte {
+	h := d.New()
+	h.Write(bs)
+	return h.Sum(nil)
+}
+
+func (d Digest) String() string {
+	switch d {
+	case DigestMD5:
+		return "MD5"
+	case DigestSHA1:
+		return "SHA1"
+	case DigestSHA256:
+		return "SHA256"
+	case DigestCRC32C:
+		return "CRC32C"
+	case DigestCRC64:
+		return "CRC64"
+	}
+	return fmt.Sprintf("Digest(%d)", int(d))
+}
+
+// DigestMismatchError reports a sink that delivered bytes that do not match
+// the digest of the source.
+type DigestMismatchError struct {
+	Digest Digest
+	Want   []byte
+	Got    []byte
+}
+
+func (e *DigestMismatchError) Error() string {
+	return fmt.Sprintf("%v mismatch: want %x, got %x", e.Digest, e.Want, e.Got)
+}
+
+// digester hashes the source as blocks are read from it.
+type digester struct {
+	ds   []Digest
+	hs   []hash.Hash
+	sums map[Digest][]byte
+	// done is closed once sums is complete
+	done chan struct{}
+}
+
+// SetDigests computes the digests ds over the source as it is read.  Each
+// digest is computed once, whatever the number of sinks, and is available
+// from Sum after the source reaches EOF.
+//
+// SetDigests must be called before the first read.
+func (mr *MultiplexReader) SetDigests(ds ...Digest) {
+	g := &digester{
+		sums: map[Digest][]byte{},
+		done: make(chan struct{}),
+	}
+	for _, d := range ds {
+		g.ds = append(g.ds, d)
+		g.hs = append(g.hs, d.New())
+	}
+	mr.mtx.Lock()
+	defer mr.mtx.Unlock()
+	if mr.baseBi > 0 {
+		panic("late start")
+	}
+	mr.digest = g
+}
+
+// write hashes the next block read from the source.  called with the lock
+// held.
+func (g *digester) write(bs []byte, err error) {
+	if g.hs == nil {
+		return
+	}
+	for _, h := range g.hs {
+		h.Write(bs)
+	}
+	if err == nil {
+		return
+	}
+	if err == io.EOF {
+		for i, d := range g.ds {
+			g.sums[d] = g.hs[i].Sum(nil)
+		}
+		close(g.done)
+	}
+	// the source has ended, successfully or not
+	g.hs = nil
+}
+
+// Sum returns the digest d of the source.  It returns ErrDigestUnavailable
+// if d was not set with SetDigests or the source has not reached EOF.
+func (mr *MultiplexReader) Sum(d Digest) ([]byte, error) {
+	g := mr.digest
+	if g == nil {
+		return nil, ErrDigestUnavailable
+	}
+	select {
+	case <-g.done:
+	default:
+		return nil, ErrDigestUnavailable
+	}
+	sum, ok := g.sums[d]
+	if !ok {
+		return nil, ErrDigestUnavailable
+	}
+	return append([]byte(nil), sum...), nil
+}
+
+// VerifyingReader is a sink that checks the bytes it delivers against a digest
+// of the source.
+type VerifyingReader struct {
+	r *Reader
+	d Digest
+	h hash.Hash
+}
+
+// NewVerifyingReader wraps the sink r so that, at EOF, the bytes it delivered
+// are compared with the digest d of the source.  A difference is returned in
+// place of io.EOF as a *DigestMismatchError.  d must be set on the source with
+// SetDigests, and r must deliver the whole source.
+func NewVerifyingReader(r *Reader, d Digest) *VerifyingReader {
+	return &VerifyingReader{
+		r: r,
+		d: d,
+		h: d.New(),
+	}
+}
+
+// Read fulfills the io.Reader interface
+func (v *VerifyingReader) Read(bs []byte) (int, error) {
+	n, err := v.r.Read(bs)
+	v.h.Write(bs[:n])
+	if err != io.EOF {
+		return n, err
+	}
+	want, serr := v.r.mr.Sum(v.d)
+	if serr != nil {
+		return n, serr
+	}
+	if got := v.h.Sum(nil); !bytes.Equal(want, got) {
+		return n, &DigestMismatchError{
+			Digest: v.d,
+			Want:   want,
+			Got:    got,
+		}
+	}
+	return n, err
+}
+
+// Close closes the underlying sink.
+func (v *VerifyingReader) Close() error {
+	return v.r.Close()
+}
@@ -0,0 +1,115 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDigests(t *testing.T) {
+
+	ds := []Digest{DigestMD5, DigestSHA1, DigestSHA256, DigestCRC32C, DigestCRC64}
+	mr := NewMultiplexReaderWithSize(strings.NewReader(LONG_GREEK), 100)
+	mr.SetDigests(ds...)
+
+	N := 3
+	rs := make([]*VerifyingReader, N)
+	for i := 0; i < N; i++ {
+		rs[i] = NewVerifyingReader(mr.NewReaderWithLength(4), DigestSHA256)
+	}
+	if _, err := mr.Sum(DigestSHA256); err != ErrDigestUnavailable {
+		t.Fatalf("unexpected value: %v", err)
+	}
+
+	wg := sync.WaitGroup{}
+	errs := make([]error, N)
+	for i := 0; i < N; i++ {
+		wg.Add(1)
+		go func(j int) {
+			defer wg.Done()
+			_, errs[j] = io.Copy(ioutil.Discard, rs[j])
+			rs[j].Close()
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < N; i++ {
+		if errs[i] != nil {
+			t.Fatalf("err: %v", errs[i])
+		}
+	}
+	for _, d := range ds {
+		h := d.New()
+		h.Write([]byte(LONG_GREEK))
+		sum, err := mr.Sum(d)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !bytes.Equal(sum, h.Sum(nil)) {
+			t.Fatalf("unexpected value: %v", d)
+		}
+	}
+
+}
+
+func TestDigestMismatch(t *testing.T) {
+
+	mr := NewMultiplexReaderWithSize(strings.NewReader(LONG_GREEK), 100)
+	mr.SetDigests(DigestCRC32C)
+
+	// a sink missing the start of the source does not match
+	r0 := NewVerifyingReader(mr.NewRangeReader(10, -1), DigestCRC32C)
+	r1 := NewVerifyingReader(mr.NewReader(), DigestMD5)
+
+	wg := sync.WaitGroup{}
+	var err0, err1 error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, err0 = io.Copy(ioutil.Discard, r0)
+		r0.Close()
+	}()
+	go func() {
+		defer wg.Done()
+		_, err1 = io.Copy(ioutil.Discard, r1)
+		r1.Close()
+	}()
+	wg.Wait()
+
+	merr, ok := err0.(*DigestMismatchError)
+	if !ok {
+		t.Fatalf("unexpected value: %v", err0)
+	}
+	if merr.Digest != DigestCRC32C || bytes.Equal(merr.Want, merr.Got) {
+		t.Fatalf("unexpected value")
+	}
+	if err1 != ErrDigestUnavailable {
+		t.Fatalf("unexpected value: %v", err1)
+	}
+
+}
@@ -83,6 +83,24 @@ func ExampleFile() {
 }
 
 
+// ExampleCopyFiles shows replication of a random stream to two files that are
+// replaced only if both are written in full
+func ExampleCopyFiles() {
+
+	r := rand.New(rand.NewSource(time.Now().Unix()))
+
+	n, err := CopyFiles(io.LimitReader(r, 1<<24), 0, "example1.dat", "example2.dat")
+	if err != nil {
+		log.Fatalf("err: %v\n", err)
+	}
+	fmt.Printf("copied %d\n", n)
+
+	//Output:
+	//copied 16777216
+
+}
+
+
 // ExampleFileWithJam example shows freeing readers blocked by fill buffer channels.
 func ExampleFileWithJam() {
 
@@ -0,0 +1,63 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import "time"
+
+// SetLowLatency distributes source reads without waiting for full blocks.
+// With a zero maxDelay the result of every source read is distributed as soon
+// as it returns.  Otherwise a block is distributed once it is full or
+// maxDelay has passed since its first byte was read, whichever is first.
+// This suits interactive streams such as terminal sessions and log tails.
+// Blocks are still bounded by the block size and SetLowLatency takes
+// precedence over SetAdaptiveSize.
+//
+// SetLowLatency must be called before the first read.
+func (mr *MultiplexReader) SetLowLatency(maxDelay time.Duration) {
+	mr.mtx.Lock()
+	defer mr.mtx.Unlock()
+	if mr.baseBi > 0 {
+		panic("late start")
+	}
+	mr.lowLatency = true
+	mr.maxDelay = maxDelay
+	if mr.async == nil {
+		mr.async = &asyncReader{rdr: mr.rdr}
+	}
+}
+
+// nextPartial reads the next block without waiting for it to fill.
+func (mr *MultiplexReader) nextPartial() ([]byte, error) {
+	brs := make([]byte, mr.blocksizeB)
+	if mr.maxDelay > 0 {
+		nn, _, err := mr.async.fill(brs, 0, mr.maxDelay)
+		return brs[:nn], err
+	}
+	for {
+		// skip empty reads.  a read into an empty block is always empty
+		n, err := mr.async.read(brs)
+		if n > 0 || err != nil || len(brs) == 0 {
+			return brs[:n], err
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestLowLatency(t *testing.T) {
+
+	pr, pw := io.Pipe()
+	mr := NewMultiplexReader(pr)
+	mr.SetLowLatency(0)
+	r0 := mr.NewReader()
+	r1 := mr.NewReader()
+
+	bs := make([]byte, 1<<10)
+	for _, s := range []string{"ls\n", "total 0\n", "$ "} {
+		// each write is delivered before the next is made
+		go pw.Write([]byte(s))
+		n, err := r0.Read(bs)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if string(bs[:n]) != s {
+			t.Fatalf("unexpected value: %q", bs[:n])
+		}
+		n, err = r1.Read(bs)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if string(bs[:n]) != s {
+			t.Fatalf("unexpected value: %q", bs[:n])
+		}
+	}
+	pw.Close()
+	n, err := r0.Read(bs)
+	if n != 0 || err != io.EOF {
+		t.Fatalf("unexpected value: %d %v", n, err)
+	}
+
+}
+
+func TestLowLatencyDelay(t *testing.T) {
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write([]byte("a"))
+		pw.Write([]byte("b"))
+		pw.Write([]byte("c"))
+		time.Sleep(time.Millisecond * 100)
+		pw.Write([]byte("d"))
+		pw.Close()
+	}()
+
+	mr := NewMultiplexReader(pr)
+	mr.SetLowLatency(time.Millisecond * 20)
+	r0 := mr.NewReader()
+
+	bs := make([]byte, 1<<10)
+	n, err := r0.Read(bs)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(bs[:n]) != "abc" {
+		t.Fatalf("unexpected value: %q", bs[:n])
+	}
+	n, err = r0.Read(bs)
+	if err != nil && err != io.EOF {
+		t.Fatalf("err: %v", err)
+	}
+	if string(bs[:n]) != "d" {
+		t.Fatalf("unexpected value: %q", bs[:n])
+	}
+
+}
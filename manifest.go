@@ -0,0 +1,223 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Manifest writes a checksum manifest of replicated objects in the format of
+// sha256sum and its relatives, or in the tagged BSD format written by their
+// --tag option.
+type Manifest struct {
+	mtx    sync.Mutex
+	w      io.Writer
+	d      Digest
+	tagged bool
+}
+
+// NewManifest creates a Manifest that writes lines of digest d to w, in the
+// BSD format if tagged.
+func NewManifest(w io.Writer, d Digest, tagged bool) *Manifest {
+	return &Manifest{
+		w:      w,
+		d:      d,
+		tagged: tagged,
+	}
+}
+
+// Replicate copies src to each of dsts as Copy does and adds a line for name
+// with the digest of src.  The line is added whenever src is read to EOF, even
+// if destinations failed.  Replicate may be called concurrently.
+func (m *Manifest) Replicate(name string, src io.Reader, dsts ...io.Writer) (int64, error) {
+	mr := NewMultiplexReader(src)
+	mr.SetDigests(m.d)
+	nn, err := mr.copyTo(dsts)
+	sum, serr := mr.Sum(m.d)
+	if serr != nil {
+		return nn, err
+	}
+	if aerr := m.Add(name, sum); err == nil {
+		err = aerr
+	}
+	return nn, err
+}
+
+// Add adds a line for name with the digest sum.
+func (m *Manifest) Add(name string, sum []byte) error {
+	esc := strings.ContainsAny(name, "\\\n\r")
+	if esc {
+		name = strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r").Replace(name)
+	}
+	var line string
+	if m.tagged {
+		line = fmt.Sprintf("%v (%s) = %x\n", m.d, name, sum)
+	} else {
+		line = fmt.Sprintf("%x  %s\n", sum, name)
+	}
+	if esc {
+		// a leading backslash marks an escaped name
+		line = "\\" + line
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	_, err := io.WriteString(m.w, line)
+	return err
+}
+
+// ManifestError reports the objects of a manifest that failed verification.
+type ManifestError struct {
+	// Names lists the objects that failed, and Errs holds the error for
+	// each: a *DigestMismatchError or the error opening or reading it.
+	Names []string
+	Errs  []error
+}
+
+func (e *ManifestError) Error() string {
+	return fmt.Sprintf("%d objects failed verification: %s: %v", len(e.Names), e.Names[0], e.Errs[0])
+}
+
+// VerifyManifest reads a manifest from r, in either format, and checks the
+// digest of each object named in it read from open.  Digests of untagged lines
+// are told apart by their length.  It returns a *ManifestError listing the
+// objects that do not match, or an error if the manifest is malformed.
+func VerifyManifest(r io.Reader, open func(name string) (io.ReadCloser, error)) error {
+	merr := &ManifestError{}
+	sc := bufio.NewScanner(r)
+	for i := 1; sc.Scan(); i++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		name, d, want, err := parseManifestLine(sc.Text())
+		if err != nil {
+			return fmt.Errorf("manifest line %d: %v", i, err)
+		}
+		if err := verifyObject(name, d, want, open); err != nil {
+			merr.Names = append(merr.Names, name)
+			merr.Errs = append(merr.Errs, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	if len(merr.Names) > 0 {
+		return merr
+	}
+	return nil
+}
+
+func verifyObject(name string, d Digest, want []byte, open func(name string) (io.ReadCloser, error)) error {
+	rc, err := open(name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	h := d.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return err
+	}
+	if got := h.Sum(nil); !bytes.Equal(want, got) {
+		return &DigestMismatchError{
+			Digest: d,
+			Want:   want,
+			Got:    got,
+		}
+	}
+	return nil
+}
+
+var manifestDigests = []Digest{DigestMD5, DigestSHA1, DigestSHA256, DigestCRC32C, DigestCRC64}
+
+func parseManifestLine(line string) (name string, d Digest, sum []byte, err error) {
+	esc := strings.HasPrefix(line, "\\")
+	if esc {
+		line = line[1:]
+	}
+	var hx string
+	if i := strings.Index(line, " ("); i > 0 && !strings.Contains(line[:i], " ") {
+		// tagged: DIGEST (name) = hex
+		j := strings.LastIndex(line, ") = ")
+		if j < i {
+			return "", 0, nil, fmt.Errorf("malformed")
+		}
+		tag := line[:i]
+		for _, md := range manifestDigests {
+			if md.String() == tag {
+				d = md
+			}
+		}
+		if d == 0 {
+			return "", 0, nil, fmt.Errorf("unknown digest %q", tag)
+		}
+		name, hx = line[i+2:j], line[j+4:]
+	} else {
+		// untagged: hex, a space, a space or '*' for binary, name
+		i := strings.IndexByte(line, ' ')
+		if i < 0 || i+2 > len(line) {
+			return "", 0, nil, fmt.Errorf("malformed")
+		}
+		hx, name = line[:i], line[i+2:]
+		for _, md := range manifestDigests {
+			if md.New().Size()*2 == len(hx) {
+				d = md
+			}
+		}
+		if d == 0 {
+			return "", 0, nil, fmt.Errorf("unknown digest length %d", len(hx))
+		}
+	}
+	sum, err = hex.DecodeString(hx)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	if esc {
+		name = unescapeName(name)
+	}
+	return name, d, sum, nil
+}
+
+func unescapeName(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
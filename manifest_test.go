@@ -0,0 +1,102 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+
+	objs := map[string]string{
+		"greek/short": SHORT_GREEK,
+		"greek/long":  LONG_GREEK,
+		"odd\nname":   "odd",
+	}
+	replicas := map[string]*bytes.Buffer{}
+	open := func(name string) (io.ReadCloser, error) {
+		buf, ok := replicas[name]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return ioutil.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	}
+
+	for _, tagged := range []bool{false, true} {
+		out := &bytes.Buffer{}
+		m := NewManifest(out, DigestSHA256, tagged)
+		for _, name := range []string{"greek/short", "greek/long", "odd\nname"} {
+			replicas[name] = &bytes.Buffer{}
+			_, err := m.Replicate(name, strings.NewReader(objs[name]), replicas[name], ioutil.Discard)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+		}
+
+		lines := strings.Split(out.String(), "\n")
+		want := fmt.Sprintf("%x  greek/short", sha256.Sum256([]byte(SHORT_GREEK)))
+		if tagged {
+			want = fmt.Sprintf("SHA256 (greek/short) = %x", sha256.Sum256([]byte(SHORT_GREEK)))
+		}
+		if lines[0] != want {
+			t.Fatalf("unexpected value: %q", lines[0])
+		}
+		if !strings.HasPrefix(lines[2], "\\") || !strings.Contains(lines[2], "odd\\nname") {
+			t.Fatalf("unexpected value: %q", lines[2])
+		}
+
+		if err := VerifyManifest(bytes.NewReader(out.Bytes()), open); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		replicas["greek/long"].Bytes()[10] ^= 1
+		delete(replicas, "odd\nname")
+		err := VerifyManifest(bytes.NewReader(out.Bytes()), open)
+		merr, ok := err.(*ManifestError)
+		if !ok {
+			t.Fatalf("unexpected value: %v", err)
+		}
+		if len(merr.Names) != 2 || merr.Names[0] != "greek/long" || merr.Names[1] != "odd\nname" {
+			t.Fatalf("unexpected value: %v", merr.Names)
+		}
+		if _, ok := merr.Errs[0].(*DigestMismatchError); !ok {
+			t.Fatalf("unexpected value")
+		}
+		if merr.Errs[1] != os.ErrNotExist {
+			t.Fatalf("unexpected value")
+		}
+	}
+
+	err := VerifyManifest(strings.NewReader("xyz\n"), open)
+	if _, ok := err.(*ManifestError); ok || err == nil {
+		t.Fatalf("unexpected value: %v", err)
+	}
+
+}
@@ -0,0 +1,193 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// SourceError attributes an error to one of the sources of a MergeReader.
+type SourceError struct {
+	// Index is the position of the source among those merged.
+	Index int
+	Err   error
+}
+
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("source %d: %v", e.Index, e.Err)
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// MergeReader combines many sources into one reader.  It is the inverse of
+// MultiplexReader.
+type MergeReader struct {
+	cs   []chan entry
+	ci   int
+	buf  []byte
+	err  error
+	done chan struct{}
+	once sync.Once
+}
+
+// NewMergeReader creates a reader that combines the sources rs, each read
+// concurrently in its own goroutine.  With a nil split the sources are
+// concatenated in order.  Otherwise the sources are framed into records with
+// split and whole records are interleaved in the order they arrive.  Records
+// are delivered as read, delimiters included, so each source should end its
+// last record.
+//
+// An error from a source other than io.EOF ends the merge and is returned as
+// a *SourceError.  The MergeReader must be closed to release the goroutines
+// reading sources that have not ended.
+func NewMergeReader(split bufio.SplitFunc, rs ...io.Reader) *MergeReader {
+	return NewMergeReaderWithLength(split, default_CHANNEL_LENGTH, rs...)
+}
+
+// NewMergeReaderWithLength creates a MergeReader that buffers up to `length`
+// blocks or records read ahead from each source.  See NewMergeReader.
+func NewMergeReaderWithLength(split bufio.SplitFunc, length int, rs ...io.Reader) *MergeReader {
+	m := &MergeReader{
+		done: make(chan struct{}),
+	}
+	if split == nil {
+		for i, r := range rs {
+			c := make(chan entry, length)
+			m.cs = append(m.cs, c)
+			go m.concat(i, r, c)
+		}
+		return m
+	}
+	c := make(chan entry, length*len(rs))
+	m.cs = append(m.cs, c)
+	wg := &sync.WaitGroup{}
+	for i, r := range rs {
+		wg.Add(1)
+		go func(j int, r io.Reader) {
+			defer wg.Done()
+			m.interleave(j, r, split, c)
+		}(i, r)
+	}
+	go func() {
+		wg.Wait()
+		close(c)
+	}()
+	return m
+}
+
+// concat reads blocks of source i onto its own channel.
+func (m *MergeReader) concat(i int, r io.Reader, c chan entry) {
+	defer close(c)
+	for {
+		bs := make([]byte, default_BLOCK_SIZE_B)
+		n, err := r.Read(bs)
+		if n > 0 && !m.put(c, entry{bs: bs[:n]}) {
+			return
+		}
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			m.put(c, entry{err: &SourceError{i, err}})
+			return
+		}
+	}
+}
+
+// interleave reads records of source i onto the shared channel.
+func (m *MergeReader) interleave(i int, r io.Reader, split bufio.SplitFunc, c chan entry) {
+	sc := bufio.NewScanner(r)
+	sc.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		// deliver the record as read rather than the token
+		adv, tok, err := split(data, atEOF)
+		if tok != nil && adv > 0 {
+			tok = data[:adv]
+		}
+		return adv, tok, err
+	})
+	for sc.Scan() {
+		if !m.put(c, entry{bs: append([]byte(nil), sc.Bytes()...)}) {
+			return
+		}
+	}
+	if err := sc.Err(); err != nil {
+		m.put(c, entry{err: &SourceError{i, err}})
+	}
+}
+
+// put queues an entry unless the reader has been closed.
+func (m *MergeReader) put(c chan entry, ent entry) bool {
+	select {
+	case c <- ent:
+		return true
+	case <-m.done:
+		return false
+	}
+}
+
+// Read fulfills the io.Reader interface
+func (m *MergeReader) Read(bs []byte) (int, error) {
+	for len(m.buf) == 0 {
+		if m.err != nil {
+			return 0, m.err
+		}
+		if m.ci == len(m.cs) {
+			m.err = io.EOF
+			continue
+		}
+		ent, ok := <-m.cs[m.ci]
+		if !ok {
+			// source, or all sources, ended
+			m.ci++
+			continue
+		}
+		if ent.err != nil {
+			m.err = ent.err
+			m.stop()
+			continue
+		}
+		m.buf = ent.bs
+	}
+	n := copy(bs, m.buf)
+	m.buf = m.buf[n:]
+	return n, nil
+}
+
+// Close stops reading the sources.  Reads after Close return ErrClosedReader.
+func (m *MergeReader) Close() error {
+	m.stop()
+	m.buf = nil
+	m.err = ErrClosedReader
+	return nil
+}
+
+func (m *MergeReader) stop() {
+	m.once.Do(func() {
+		close(m.done)
+	})
+}
@@ -0,0 +1,122 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMergeConcat(t *testing.T) {
+
+	rs := []io.Reader{
+		strings.NewReader(SHORT_GREEK),
+		strings.NewReader(NADA),
+		strings.NewReader(LONG_GREEK),
+		strings.NewReader(SHORT_GREEK),
+	}
+	m := NewMergeReaderWithLength(nil, 2, rs...)
+	defer m.Close()
+
+	bs, err := ioutil.ReadAll(m)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(bs) != SHORT_GREEK+LONG_GREEK+SHORT_GREEK {
+		t.Fatalf("unexpected value")
+	}
+
+}
+
+func TestMergeInterleave(t *testing.T) {
+
+	N := 3
+	M := 50
+	rs := make([]io.Reader, N)
+	want := []string{}
+	for i := 0; i < N; i++ {
+		pr, pw := io.Pipe()
+		rs[i] = pr
+		go func(j int) {
+			for k := 0; k < M; k++ {
+				// write records in pieces so that they arrive torn
+				l := fmt.Sprintf("worker %d line %d\n", j, k)
+				pw.Write([]byte(l[:5]))
+				time.Sleep(time.Microsecond * 10)
+				pw.Write([]byte(l[5:]))
+			}
+			pw.Close()
+		}(i)
+		for k := 0; k < M; k++ {
+			want = append(want, fmt.Sprintf("worker %d line %d", i, k))
+		}
+	}
+
+	m := NewMergeReader(bufio.ScanLines, rs...)
+	defer m.Close()
+
+	got := []string{}
+	sc := bufio.NewScanner(m)
+	for sc.Scan() {
+		got = append(got, sc.Text())
+	}
+	if sc.Err() != nil {
+		t.Fatalf("err: %v", sc.Err())
+	}
+	sort.Strings(got)
+	sort.Strings(want)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected value")
+	}
+
+}
+
+func TestMergeError(t *testing.T) {
+
+	m := NewMergeReader(nil,
+		strings.NewReader(SHORT_GREEK),
+		io.MultiReader(strings.NewReader(SHORT_GREEK), &errReader{errors.New("testing")}),
+		strings.NewReader(LONG_GREEK),
+	)
+	defer m.Close()
+
+	bs, err := ioutil.ReadAll(m)
+	serr, ok := err.(*SourceError)
+	if !ok {
+		t.Fatalf("unexpected value: %v", err)
+	}
+	if serr.Index != 1 || serr.Err.Error() != "testing" {
+		t.Fatalf("unexpected value: %v", serr)
+	}
+	if string(bs) != SHORT_GREEK+SHORT_GREEK {
+		t.Fatalf("unexpected value")
+	}
+
+}
@@ -0,0 +1,200 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import (
+	"bytes"
+	"io"
+)
+
+// MerkleLeaf is the checksum of one block of the source.
+type MerkleLeaf struct {
+	Offset int64
+	Length int
+	Sum    []byte
+}
+
+// MerkleTree is a hash tree over the checksums of the blocks of a source.
+// Blocks are numbered by sequence number, so Blocks[i] is the block
+// delivered with Seq i.
+type MerkleTree struct {
+	Digest Digest
+	Blocks []MerkleLeaf
+}
+
+// blockSums records the checksum of each block distributed.
+type blockSums struct {
+	d      Digest
+	leaves []MerkleLeaf
+	// done is closed once leaves is complete
+	done  chan struct{}
+	ended bool
+}
+
+// SetBlockChecksums computes the checksum d of each block distributed from the
+// source.  Sinks receive the checksum of each whole block with ReadBlock and a
+// MerkleTree over the blocks is available from MerkleTree after EOF.
+//
+// SetBlockChecksums must be called before the first read.
+func (mr *MultiplexReader) SetBlockChecksums(d Digest) {
+	d.New()
+	mr.mtx.Lock()
+	defer mr.mtx.Unlock()
+	if mr.baseBi > 0 {
+		panic("late start")
+	}
+	mr.sums = &blockSums{
+		d:    d,
+		done: make(chan struct{}),
+	}
+}
+
+// checksum returns the checksum of the block bs at offset offBi and records
+// it as the next leaf.  called with the lock held.
+func (mr *MultiplexReader) checksum(offBi int64, bs []byte) []byte {
+	s := mr.sums
+	if s == nil || s.ended || len(bs) == 0 {
+		return nil
+	}
+	sum := s.d.Sum(bs)
+	s.leaves = append(s.leaves, MerkleLeaf{
+		Offset: offBi,
+		Length: len(bs),
+		Sum:    sum,
+	})
+	return sum
+}
+
+// endChecksums completes the leaves once the source reaches EOF, before the
+// sinks receive io.EOF.  called with the lock held.
+func (mr *MultiplexReader) endChecksums(err error) {
+	s := mr.sums
+	if s == nil || s.ended || err == nil {
+		return
+	}
+	s.ended = true
+	if err == io.EOF {
+		close(s.done)
+	}
+}
+
+// MerkleTree returns the tree over the block checksums of the source.  It
+// returns ErrDigestUnavailable if SetBlockChecksums was not called or the
+// source has not reached EOF.
+func (mr *MultiplexReader) MerkleTree() (*MerkleTree, error) {
+	s := mr.sums
+	if s == nil {
+		return nil, ErrDigestUnavailable
+	}
+	select {
+	case <-s.done:
+	default:
+		return nil, ErrDigestUnavailable
+	}
+	return &MerkleTree{
+		Digest: s.d,
+		Blocks: append([]MerkleLeaf(nil), s.leaves...),
+	}, nil
+}
+
+// Root returns the root hash of the tree.  As in RFC 6962, each leaf hashes
+// a 0 byte and the checksum of its block and each interior node hashes a 1
+// byte and its children, so that a leaf cannot pass for a node.  A node
+// without a sibling is carried up a level unchanged.
+func (t *MerkleTree) Root() []byte {
+	level := make([][]byte, len(t.Blocks))
+	for i, b := range t.Blocks {
+		h := t.Digest.New()
+		h.Write([]byte{0})
+		h.Write(b.Sum)
+		level[i] = h.Sum(nil)
+	}
+	if len(level) == 0 {
+		return t.Digest.Sum(nil)
+	}
+	for len(level) > 1 {
+		next := level[:0:0]
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			h := t.Digest.New()
+			h.Write([]byte{1})
+			h.Write(level[i])
+			h.Write(level[i+1])
+			next = append(next, h.Sum(nil))
+		}
+		level = next
+	}
+	return level[0]
+}
+
+// Diff returns the indexes of the blocks of t that differ from those of o,
+// including blocks missing from o and, as indexes of o, blocks missing from t.
+func (t *MerkleTree) Diff(o *MerkleTree) []int {
+	var is []int
+	n := len(t.Blocks)
+	if len(o.Blocks) > n {
+		n = len(o.Blocks)
+	}
+	for i := 0; i < n; i++ {
+		if i >= len(t.Blocks) || i >= len(o.Blocks) || !t.Blocks[i].equal(o.Blocks[i]) {
+			is = append(is, i)
+		}
+	}
+	return is
+}
+
+func (l MerkleLeaf) equal(o MerkleLeaf) bool {
+	return l.Offset == o.Offset && l.Length == o.Length && bytes.Equal(l.Sum, o.Sum)
+}
+
+// Verify reads a replica of the source from r and returns the indexes of the
+// blocks that do not match.  Blocks beyond the end of a short replica do not
+// match, and the index len(t.Blocks) is returned if the replica is longer
+// than the source.
+func (t *MerkleTree) Verify(r io.Reader) ([]int, error) {
+	var is []int
+	var bs []byte
+	for i, b := range t.Blocks {
+		if cap(bs) < b.Length {
+			bs = make([]byte, b.Length)
+		}
+		bs = bs[:b.Length]
+		n, err := io.ReadFull(r, bs)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return is, err
+		}
+		if n < b.Length || !bytes.Equal(t.Digest.Sum(bs), b.Sum) {
+			is = append(is, i)
+		}
+	}
+	n, err := r.Read(make([]byte, 1))
+	if n > 0 {
+		is = append(is, len(t.Blocks))
+	} else if err != nil && err != io.EOF {
+		return is, err
+	}
+	return is, nil
+}
@@ -0,0 +1,153 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func merkleTree(t *testing.T, src string, sizeB int) *MerkleTree {
+	mr := NewMultiplexReaderWithSize(strings.NewReader(src), sizeB)
+	mr.SetBlockChecksums(DigestSHA256)
+	r := mr.NewReader()
+	defer r.Close()
+	if _, err := mr.MerkleTree(); err != ErrDigestUnavailable {
+		t.Fatalf("unexpected value: %v", err)
+	}
+	for i := int64(0); ; i++ {
+		b, err := r.ReadBlock()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if b.Seq != i || b.Sum == nil || !bytes.Equal(b.Sum, DigestSHA256.Sum(b.Data)) {
+			t.Fatalf("unexpected value")
+		}
+	}
+	mt, err := mr.MerkleTree()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return mt
+}
+
+func TestMerkleTree(t *testing.T) {
+
+	mt := merkleTree(t, LONG_GREEK, 100)
+	if len(mt.Blocks) != (len(LONG_GREEK)+99)/100 {
+		t.Fatalf("unexpected value")
+	}
+	for i, b := range mt.Blocks {
+		if b.Offset != int64(i*100) {
+			t.Fatalf("unexpected value")
+		}
+	}
+
+	is, err := mt.Verify(strings.NewReader(LONG_GREEK))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(is) != 0 {
+		t.Fatalf("unexpected value: %v", is)
+	}
+
+	bad := []byte(LONG_GREEK)
+	bad[250] ^= 1
+	is, _ = mt.Verify(bytes.NewReader(bad))
+	if len(is) != 1 || is[0] != 2 {
+		t.Fatalf("unexpected value: %v", is)
+	}
+	is, _ = mt.Verify(strings.NewReader(LONG_GREEK + "x"))
+	if len(is) != 1 || is[0] != len(mt.Blocks) {
+		t.Fatalf("unexpected value: %v", is)
+	}
+	is, _ = mt.Verify(strings.NewReader(LONG_GREEK[:150]))
+	if len(is) != len(mt.Blocks)-1 || is[0] != 1 {
+		t.Fatalf("unexpected value: %v", is)
+	}
+
+	ot := merkleTree(t, string(bad), 100)
+	if bytes.Equal(mt.Root(), ot.Root()) {
+		t.Fatalf("unexpected value")
+	}
+	if !bytes.Equal(mt.Root(), merkleTree(t, LONG_GREEK, 100).Root()) {
+		t.Fatalf("unexpected value")
+	}
+	is = mt.Diff(ot)
+	if len(is) != 1 || is[0] != 2 {
+		t.Fatalf("unexpected value: %v", is)
+	}
+
+}
+
+func TestBlockChecksumPartial(t *testing.T) {
+
+	mr := NewMultiplexReaderWithSize(strings.NewReader(LONG_GREEK), 100)
+	mr.SetBlockChecksums(DigestCRC32C)
+	r0 := mr.NewReader()
+	r1 := mr.NewRangeReader(50, -1)
+	defer r0.Close()
+	defer r1.Close()
+
+	bs := make([]byte, 10)
+	if _, err := r0.Read(bs); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	b, err := r0.ReadBlock()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if b.Sum != nil || b.Offset != 10 {
+		t.Fatalf("unexpected value")
+	}
+	// the clipped first block has no checksum but the next does
+	b, _ = r1.ReadBlock()
+	if b.Sum != nil || b.Offset != 50 {
+		t.Fatalf("unexpected value")
+	}
+	b, _ = r1.ReadBlock()
+	if b.Sum == nil || b.Offset != 100 {
+		t.Fatalf("unexpected value")
+	}
+
+}
+
+func TestMerkleRootLeaves(t *testing.T) {
+
+	a, b := strings.Repeat("a", 100), strings.Repeat("b", 100)
+	mt := merkleTree(t, a+b, 100)
+
+	// a block holding the encoding of a node does not share its root
+	node := append([]byte{1}, DigestSHA256.Sum([]byte(a))...)
+	node = append(node, DigestSHA256.Sum([]byte(b))...)
+	ot := merkleTree(t, string(node), 100)
+	if bytes.Equal(mt.Root(), ot.Root()) {
+		t.Fatalf("unexpected value")
+	}
+
+}
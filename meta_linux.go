@@ -0,0 +1,133 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+//go:build linux
+// +build linux
+
+package multio
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"time"
+)
+
+// copyMeta gives dst the owner and extended attributes of the file src, or
+// only the owner if src is empty.  A change of owner or attribute that is not
+// permitted or not supported is skipped.  owned reports whether dst took the
+// owner of src.
+func copyMeta(src string, fi os.FileInfo, dst *os.File) (owned bool, err error) {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		err := dst.Chown(int(st.Uid), int(st.Gid))
+		if err != nil && !skippable(err) {
+			return false, err
+		}
+		owned = err == nil
+	}
+	if src == "" {
+		return owned, nil
+	}
+	names, err := xattrs(src)
+	if err != nil {
+		if skippable(err) {
+			return owned, nil
+		}
+		return owned, err
+	}
+	for _, name := range names {
+		val, err := xattr(src, name)
+		if err != nil {
+			if skippable(err) {
+				continue
+			}
+			return owned, err
+		}
+		if err := syscall.Setxattr(dst.Name(), name, val, 0); err != nil && !skippable(err) {
+			return owned, &os.PathError{Op: "setxattr", Path: dst.Name(), Err: err}
+		}
+	}
+	return owned, nil
+}
+
+// skippable reports whether err is a refusal to change metadata.
+func skippable(err error) bool {
+	if perr, ok := err.(*os.PathError); ok {
+		err = perr.Err
+	}
+	return err == syscall.EPERM || err == syscall.EACCES || err == syscall.ENOTSUP
+}
+
+// xattrs lists the names of the extended attributes of the file name.
+func xattrs(name string) ([]string, error) {
+	bs, err := growCall(func(bs []byte) (int, error) {
+		return syscall.Listxattr(name, bs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, n := range bytes.Split(bs, []byte{0}) {
+		if len(n) > 0 {
+			names = append(names, string(n))
+		}
+	}
+	return names, nil
+}
+
+// xattr returns the value of an extended attribute of the file name.
+func xattr(name, attr string) ([]byte, error) {
+	return growCall(func(bs []byte) (int, error) {
+		return syscall.Getxattr(name, attr, bs)
+	})
+}
+
+// growCall calls fn with a larger buffer until the result fits.
+func growCall(fn func(bs []byte) (int, error)) ([]byte, error) {
+	for {
+		n, err := fn(nil)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, nil
+		}
+		bs := make([]byte, n)
+		m, err := fn(bs)
+		if err == syscall.ERANGE {
+			// grew since its size was taken
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return bs[:m], nil
+	}
+}
+
+// accessTime returns the time the file was last accessed.
+func accessTime(fi os.FileInfo) time.Time {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(int64(st.Atim.Sec), int64(st.Atim.Nsec))
+	}
+	return fi.ModTime()
+}
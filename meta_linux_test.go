@@ -0,0 +1,120 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+//go:build linux
+// +build linux
+
+package multio
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestCopyFileXattrs(t *testing.T) {
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := ioutil.WriteFile(src, []byte(SHORT_GREEK), 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := syscall.Setxattr(src, "user.multio", []byte("testing"), 0); err != nil {
+		t.Skipf("xattrs unsupported: %v", err)
+	}
+
+	dst := filepath.Join(dir, "dst")
+	if _, err := CopyFileWithOptions(src, CopyOptions{}, dst); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	val, err := xattr(dst, "user.multio")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(val) != "testing" {
+		t.Fatalf("unexpected value")
+	}
+
+}
+
+// ownerInfo reports a file as owned by st.Uid
+type ownerInfo struct {
+	os.FileInfo
+	st *syscall.Stat_t
+}
+
+func (fi ownerInfo) Sys() interface{} {
+	return fi.st
+}
+
+func TestCopyFileSetuid(t *testing.T) {
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := ioutil.WriteFile(src, []byte(SHORT_GREEK), 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := os.Chmod(src, 0755|os.ModeSetuid|os.ModeSetgid); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	fi, err := os.Stat(src)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// a source owned by another user
+	other := 0
+	if os.Getuid() == 0 {
+		other = 65534
+	}
+	st := *fi.Sys().(*syscall.Stat_t)
+	st.Uid = uint32(other)
+	permitted := os.Chown(src, other, -1) == nil
+
+	dst := filepath.Join(dir, "dst")
+	a, err := CreateAtomic(dst, 0600)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	a.Write([]byte(SHORT_GREEK))
+	m := &metaFile{AtomicFile: a, fi: ownerInfo{fi, &st}}
+	if err := m.Prepare(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := m.Commit(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// setuid and setgid are only kept along with the owner
+	dfi, err := os.Stat(dst)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	kept := dfi.Mode()&(os.ModeSetuid|os.ModeSetgid) != 0
+	owned := dfi.Sys().(*syscall.Stat_t).Uid == uint32(other)
+	if kept != permitted || owned != permitted || dfi.Mode().Perm() != 0755 {
+		t.Fatalf("unexpected value: %v %v", dfi.Mode(), permitted)
+	}
+
+}
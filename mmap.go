@@ -0,0 +1,176 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// NewMappedMultiplexReader creates a new source reader for the regular file f
+// from its current offset.  The file is mapped into memory and sinks receive
+// blocks of the mapping itself, so the source is not copied until the sinks
+// read it.  The mapping is released once every sink has been closed and the
+// reads in progress on them have returned, after which slices of it, such as
+// those returned by Bytes, must not be used.  The file must not be truncated
+// while it is mapped.
+//
+// NewMultiplexReader does not map files itself: the mapping covers the file
+// as it is when the source is created and the file offset is left unchanged.
+//
+// If f cannot be mapped, it is read as by NewMultiplexReader.
+func NewMappedMultiplexReader(f *os.File) *MultiplexReader {
+	return NewMappedMultiplexReaderWithSize(f, default_BLOCK_SIZE_B)
+}
+
+// NewMappedMultiplexReaderWithSize creates a new source reader for the regular
+// file f that distributes blocks of `size` bytes.  See
+// NewMappedMultiplexReader.
+func NewMappedMultiplexReaderWithSize(f *os.File, sizeB int) *MultiplexReader {
+	if m := mapFile(f); m != nil {
+		mr := NewMultiplexReaderWithSize(m, sizeB)
+		mr.mapped = m
+		return mr
+	}
+	return NewMultiplexReaderWithSize(f, sizeB)
+}
+
+// mapping is a file mapped into memory.  offBi is the offset of the next
+// block.  users counts the sink reads in progress, which hold off unmapping
+// once the mapping is released.
+type mapping struct {
+	mtx      sync.Mutex
+	data     []byte
+	offBi    int
+	unmap    func([]byte) error
+	users    int
+	released bool
+}
+
+// mapFile maps f from its current offset, or returns nil if it cannot.
+func mapFile(f *os.File) *mapping {
+	fi, err := f.Stat()
+	if err != nil || !fi.Mode().IsRegular() || fi.Size() == 0 || int64(int(fi.Size())) != fi.Size() {
+		return nil
+	}
+	off, err := f.Seek(0, io.SeekCurrent)
+	if err != nil || off >= fi.Size() {
+		return nil
+	}
+	data, unmap, err := mmap(f, int(fi.Size()))
+	if err != nil {
+		return nil
+	}
+	return &mapping{
+		data:  data,
+		offBi: int(off),
+		unmap: unmap,
+	}
+}
+
+// Read fulfills the io.Reader interface for use outside of a MultiplexReader.
+func (m *mapping) Read(bs []byte) (int, error) {
+	brs, err := m.readBlock(len(bs))
+	return copy(bs, brs), err
+}
+
+func (m *mapping) readBlock(sizeB int) ([]byte, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.released {
+		return nil, ErrClosedReader
+	}
+	if m.offBi >= len(m.data) {
+		return nil, io.EOF
+	}
+	end := m.offBi + sizeB
+	if end > len(m.data) {
+		end = len(m.data)
+	}
+	// the capacity is limited so that appending to a block copies it
+	// rather than writing to the read only mapping
+	bs := m.data[m.offBi:end:end]
+	m.offBi = end
+	if m.offBi == len(m.data) {
+		return bs, io.EOF
+	}
+	return bs, nil
+}
+
+// release unmaps a mapped source once every sink has been closed.  called
+// with the lock held.
+func (mr *MultiplexReader) release() {
+	m := mr.mapped
+	if m == nil {
+		return
+	}
+	for _, r := range mr.sinks {
+		if !r.closed {
+			return
+		}
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.released {
+		return
+	}
+	m.released = true
+	if m.users == 0 {
+		m.unmap(m.data)
+		m.data = nil
+	}
+}
+
+// hold keeps a mapped source mapped while a sink reads slices of it.  it
+// returns false if the mapping has been released.  every successful hold is
+// paired with a call to unhold.
+func (mr *MultiplexReader) hold() bool {
+	m := mr.mapped
+	if m == nil {
+		return true
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.released {
+		return false
+	}
+	m.users++
+	return true
+}
+
+// unhold ends a hold, unmapping the source if it was released during the
+// read.
+func (mr *MultiplexReader) unhold() {
+	m := mr.mapped
+	if m == nil {
+		return
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.users--
+	if m.users == 0 && m.released && m.data != nil {
+		m.unmap(m.data)
+		m.data = nil
+	}
+}
@@ -0,0 +1,221 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func mappedFile(t *testing.T, content string) *os.File {
+	name := filepath.Join(t.TempDir(), "src")
+	if err := ioutil.WriteFile(name, []byte(content), 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return f
+}
+
+// mappings counts the mappings of the file name in the process, or returns -1
+// where they cannot be listed.
+func mappings(name string) int {
+	bs, err := ioutil.ReadFile("/proc/self/maps")
+	if err != nil {
+		return -1
+	}
+	return strings.Count(string(bs), name)
+}
+
+func TestMapped(t *testing.T) {
+
+	content := strings.Repeat(LONG_GREEK, 50)
+	f := mappedFile(t, content)
+	defer f.Close()
+	f.Seek(7, io.SeekStart)
+
+	mr := NewMappedMultiplexReaderWithSize(f, 100)
+	m, ok := mr.rdr.(*mapping)
+	if runtime.GOOS == "linux" && !ok {
+		t.Fatalf("unexpected value")
+	}
+
+	N := 3
+	rs := make([]*Reader, N)
+	for i := 0; i < N; i++ {
+		rs[i] = mr.NewReaderWithLength(4)
+	}
+	wg := sync.WaitGroup{}
+	got := make([]string, N)
+	for i := 0; i < N; i++ {
+		wg.Add(1)
+		go func(j int) {
+			defer wg.Done()
+			bs, err := ioutil.ReadAll(rs[j])
+			if err != nil {
+				t.Errorf("err: %v", err)
+			}
+			got[j] = string(bs)
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < N; i++ {
+		if got[i] != content[7:] {
+			t.Fatalf("unexpected value")
+		}
+	}
+
+	// released only once every sink is closed
+	for i := 0; i < N; i++ {
+		if ok && m.data == nil {
+			t.Fatalf("unexpected value")
+		}
+		rs[i].Close()
+	}
+	if ok && m.data != nil {
+		t.Fatalf("unexpected value")
+	}
+
+}
+
+// stallWriter blocks in Write until released, then reads what it was given
+type stallWriter struct {
+	in      chan struct{}
+	release chan struct{}
+	n       int
+}
+
+func (w *stallWriter) Write(bs []byte) (int, error) {
+	if w.in != nil {
+		close(w.in)
+		w.in = nil
+		<-w.release
+	}
+	for _, b := range bs {
+		w.n += int(b)
+	}
+	return len(bs), nil
+}
+
+func TestMappedCloseWhileReading(t *testing.T) {
+
+	f := mappedFile(t, strings.Repeat(LONG_GREEK, 50))
+	defer f.Close()
+
+	mr := NewMappedMultiplexReaderWithSize(f, 100)
+	m, ok := mr.rdr.(*mapping)
+	if runtime.GOOS == "linux" && !ok {
+		t.Fatalf("unexpected value")
+	}
+	r0 := mr.NewReader()
+
+	in := make(chan struct{})
+	w := &stallWriter{in: in, release: make(chan struct{})}
+	done := make(chan error)
+	go func() {
+		_, err := r0.WriteTo(w)
+		done <- err
+	}()
+	<-in
+
+	// closing the sink mid-write holds the mapping until the write returns
+	r0.Close()
+	if ok && m.data == nil {
+		t.Fatalf("unexpected value")
+	}
+	close(w.release)
+	<-done
+	if ok && m.data != nil {
+		t.Fatalf("unexpected value")
+	}
+	if _, err := r0.Read(make([]byte, 1)); err == nil {
+		t.Fatalf("unexpected value")
+	}
+
+}
+
+func TestMappedRecords(t *testing.T) {
+
+	lines := []string{}
+	for i := 0; i < 200; i++ {
+		lines = append(lines, strings.Repeat("x", i%37))
+	}
+	f := mappedFile(t, strings.Join(lines, "\n")+"\n")
+	defer f.Close()
+
+	// records span blocks of the mapping and are carried between them
+	mr := NewMappedMultiplexReaderWithSize(f, 16)
+	mr.SetSplit(bufio.ScanLines, 0)
+	r := mr.NewReader()
+	defer r.Close()
+	i := 0
+	for ; r.Scan(); i++ {
+		if r.Text() != lines[i] {
+			t.Fatalf("unexpected value: %q", r.Text())
+		}
+	}
+	if r.Err() != nil {
+		t.Fatalf("err: %v", r.Err())
+	}
+	if i != len(lines) {
+		t.Fatalf("unexpected value")
+	}
+
+}
+
+func TestMappedFallback(t *testing.T) {
+
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer pr.Close()
+	go func() {
+		pw.Write([]byte(LONG_GREEK))
+		pw.Close()
+	}()
+
+	mr := NewMappedMultiplexReader(pr)
+	if _, ok := mr.rdr.(*mapping); ok {
+		t.Fatalf("unexpected value")
+	}
+	r := mr.NewReader()
+	defer r.Close()
+	bs, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(bs) != LONG_GREEK {
+		t.Fatalf("unexpected value")
+	}
+
+}
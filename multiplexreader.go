@@ -23,8 +23,12 @@
 package multio
 
 import (
+	"bufio"
 	"errors"
 	"io"
+	"os"
+	"sync"
+	"time"
 )
 
 // need a channel based mutex to control access to source
@@ -54,9 +58,12 @@ const (
 var ErrClosedReader = errors.New("closed multireader")
 
 type entry struct {
-	i   int64
-	err error
-	bs  []byte
+	i    int64
+	seq  int64
+	err  error
+	bs   []byte
+	sum  []byte
+	hole bool
 }
 
 // MultiplexReader is a structure that allows replication of a source reader to many sink readers
@@ -65,7 +72,25 @@ type MultiplexReader struct {
 	rdr        io.Reader
 	mtx        mutex
 	baseBi     int64
-	cs         map[chan entry]chan entry
+	cs         map[chan entry]*Reader
+	parent     *Reader
+	closeSrc   bool
+	adapt      *AdaptiveSize
+	async      *asyncReader
+	lowLatency bool
+	maxDelay   time.Duration
+	split      bufio.SplitFunc
+	maxRecordB int
+	carry      []byte
+	choose     Chooser
+	sinks      []*Reader
+	seq        int64
+	digest     *digester
+	sums       *blockSums
+	packet     bool
+	sparse     bool
+	zeros      []byte
+	mapped     *mapping
 }
 
 // NewMultiplexReader creates a new source reader
@@ -79,7 +104,7 @@ func NewMultiplexReaderWithSize(r io.Reader, sizeB int) *MultiplexReader {
 		blocksizeB: sizeB,
 		rdr:        r,
 		mtx:        newMutex(),
-		cs:         map[chan entry]chan entry{},
+		cs:         map[chan entry]*Reader{},
 	}
 	return q
 }
@@ -88,10 +113,20 @@ func NewMultiplexReaderWithSize(r io.Reader, sizeB int) *MultiplexReader {
 type Reader struct {
 	mr     *MultiplexReader
 	baseBi int64
+	seq    int64
+	offBi  int64
+	endBi  int64
 	c      chan entry
+	q      []entry
 	buf    []byte
 	closed bool
 	err    error
+	once   sync.Once
+	tok    []byte
+	tokErr error
+	sum    []byte
+	batchB int
+	hole   bool
 }
 
 // NewReader creates a new sink Reader from a MultiplexReader source
@@ -102,17 +137,28 @@ func (mr *MultiplexReader) NewReader() *Reader {
 // NewReaderWithLength creates a new sink Reader with the specified channel length.
 // channel lenght must be greater than zero or the reader will deadlock on read.
 func (mr *MultiplexReader) NewReaderWithLength(length int) *Reader {
+	return mr.newReader(length, 0, -1)
+}
+
+// newReader creates a sink for the bytes from offBi up to endBi.  endBi is
+// negative for sinks that read to the end of the source.
+func (mr *MultiplexReader) newReader(length int, offBi, endBi int64) *Reader {
 	q := &Reader{
-		mr:  mr,
-		c:   make(chan entry, length),
-		buf: []byte{},
+		mr:     mr,
+		baseBi: offBi,
+		offBi:  offBi,
+		endBi:  endBi,
+		c:      make(chan entry, length),
+		buf:    []byte{},
+		batchB: default_WRITE_BATCH_B,
 	}
 	mr.mtx.Lock()
 	defer mr.mtx.Unlock()
-	if mr.baseBi > 0 {
+	if mr.baseBi > offBi {
 		panic("late start")
 	}
-	mr.cs[q.c] = q.c
+	mr.cs[q.c] = q
+	mr.sinks = append(mr.sinks, q)
 	return q
 }
 
@@ -133,15 +179,21 @@ func (r *Reader) remove() {
 	delete(r.mr.cs, r.c)
 	r.closed = true
 	r.buf = nil
+	r.q = nil
 }
 
 // CloseWithError closes the reader with the supplied error.  See Close.
 func (r *Reader) CloseWithError(err error) error {
-	// close outside of lock.  this allows readers to break stalls by calling Close()
-	close(r.c)
-	r.mr.mtx.Lock()
+	// drain the channel while waiting for the lock.  this allows readers to
+	// break stalls by calling Close()
+	r.lockDraining()
 	defer r.mr.mtx.Unlock()
+	r.once.Do(func() { close(r.c) })
 	r.remove()
+	if len(r.mr.cs) == 0 {
+		r.mr.detach(err)
+	}
+	r.mr.release()
 	r.err = err
 	if err == nil {
 		r.err = ErrClosedReader
@@ -150,10 +202,54 @@ func (r *Reader) CloseWithError(err error) error {
 	return err
 }
 
+// lockDraining takes the lock of the source, discarding entries sent to the
+// sink meanwhile so that a sink holding the lock while blocked on the full
+// channel of this one is released.
+func (r *Reader) lockDraining() {
+	for {
+		select {
+		case _, ok := <-r.c:
+			if !ok {
+				// already closed
+				r.mr.mtx.Lock()
+				return
+			}
+		case r.mr.mtx <- struct{}{}:
+			return
+		}
+	}
+}
+
 func (r *Reader) WriteTo(w io.Writer) (nn int64, err error) {
+	f, isFile := w.(*os.File)
+	if isFile && r.mr.sparse {
+		// holes are only skipped in files that can seek over them
+		isFile = seekable(f)
+	}
+	holes := false
 	for err == nil {
 		n := 0
+		var extra int64
+		var last *entry
 		n, err = r.read(func() (int, error) {
+			if r.hole && isFile {
+				// seek over zeros rather than writing them
+				holes = true
+				if werr := writeHole(f, r.buf); werr != nil {
+					return 0, werr
+				}
+				return len(r.buf), r.err
+			}
+			if r.batchB > len(r.buf) && r.err == nil && !r.mr.packet {
+				// write queued blocks along with this one
+				var bn int
+				var werr error
+				bn, extra, last, werr = r.writeBatch(w, isFile)
+				if werr == nil && last != nil {
+					werr = last.err
+				}
+				return bn, werr
+			}
 			wn, werr := w.Write(r.buf)
 			if wn == len(r.buf) {
 				return wn, r.err
@@ -163,7 +259,14 @@ func (r *Reader) WriteTo(w io.Writer) (nn int64, err error) {
 			}
 			return wn, io.ErrShortWrite
 		})
-		nn += int64(n)
+		nn += int64(n) + extra
+		if last != nil {
+			r.skip(last)
+		}
+	}
+	if err == io.EOF && holes {
+		// a file ending in a hole is extended over it
+		err = endHoles(f)
 	}
 	if err == io.EOF {
 		return nn, nil
@@ -182,20 +285,92 @@ func (mr *MultiplexReader) fill(bs []byte) (nn int, err error) {
 	return nn, err
 }
 
-func (mr *MultiplexReader) distribute(bs []byte, err error) {
-	for c := range mr.cs {
-		func() {
-			defer func() {
-				// ignore panics on channel send
-				recover()
-			}()
-			c <- entry{
-				i:   mr.baseBi,
-				bs:  bs,
-				err: err,
-			}
-		}()
+// advance reads the next block from the source and distributes it to the
+// sinks.  called with the lock held by the sink r.
+func (mr *MultiplexReader) advance(r *Reader) {
+	brs, err := mr.next()
+	if mr.digest != nil {
+		mr.digest.write(brs, err)
+	}
+	// brs now has the new bytes and err is any error resulting from the last read
+	// distribute the new buffer and error to all the readers
+	mr.distribute(brs, err, r)
+	mr.baseBi += int64(len(brs))
+}
+
+// blockReader is implemented by sources that produce whole blocks themselves,
+// avoiding the copy into a new buffer made by fill.
+type blockReader interface {
+	readBlock(sizeB int) ([]byte, error)
+}
+
+// next reads the next block from the source.
+func (mr *MultiplexReader) next() ([]byte, error) {
+	if mr.split != nil {
+		return mr.nextRecords()
+	}
+	return mr.nextBlock()
+}
+
+// nextBlock reads the next block from the source without regard to records.
+func (mr *MultiplexReader) nextBlock() ([]byte, error) {
+	if mr.lowLatency {
+		return mr.nextPartial()
+	}
+	if mr.adapt != nil {
+		return mr.nextAdaptive()
+	}
+	if br, ok := mr.rdr.(blockReader); ok {
+		return br.readBlock(mr.blocksizeB)
+	}
+	brs := make([]byte, mr.blocksizeB)
+	// fill the buffer until error or blocksize
+	nn, err := mr.fill(brs)
+	return brs[:nn], err
+}
+
+// distribute queues a block for the sinks.  entries for the sink that read
+// the block are queued privately on it: it cannot receive from its channel
+// while it holds the lock.
+func (mr *MultiplexReader) distribute(bs []byte, err error, self *Reader) {
+	if mr.choose != nil {
+		mr.balance(bs, err, self)
+		return
+	}
+	seq := mr.seq
+	mr.seq++
+	bs, hole := mr.hole(bs)
+	sum := mr.checksum(mr.baseBi, bs)
+	mr.endChecksums(err)
+	for c, r := range mr.cs {
+		ent, ok := r.clip(mr.baseBi, bs, err)
+		if !ok {
+			continue
+		}
+		ent.seq = seq
+		ent.hole = hole
+		if len(ent.bs) == len(bs) {
+			ent.sum = sum
+		}
+		send(r, ent, self)
+		if r.endBi >= 0 && mr.baseBi+int64(len(bs)) >= r.endBi {
+			// range complete.  stop queueing blocks for this sink
+			delete(mr.cs, c)
+		}
+	}
+}
+
+// send queues an entry for sink r.  self is the sink holding the lock.
+func send(r *Reader, ent entry, self *Reader) {
+	if r == self {
+		r.q = append(r.q, ent)
+		return
 	}
+	defer func() {
+		// ignore panics on channel send
+		recover()
+	}()
+	r.c <- ent
 }
 
 // Read fulfills the io.Reader interface
@@ -210,50 +385,90 @@ func (r *Reader) Read(bs []byte) (nn int, err error) {
 
 // Read fulfills the io.Reader interface
 func (r *Reader) read(coutfn func() (int, error)) (nn int, err error) {
+	if !r.mr.hold() {
+		return 0, ErrClosedReader
+	}
+	defer r.mr.unhold()
 	// nothing left in the buffer, go to the channel
 	// and get the next buffer if available.
 	l := len(r.buf)
 	if l == 0 && r.err != nil {
 		return 0, r.err
 	}
-	if l != 0 {
-		nn, err = coutfn()
-		r.buf = r.buf[nn:]
-		r.baseBi += int64(nn)
-		return nn, err
+	if l == 0 {
+		ent, err := r.next()
+		if err != nil {
+			return 0, err
+		}
+		r.baseBi = ent.i
+		r.seq = ent.seq
+		r.buf = ent.bs
+		r.err = ent.err
+		r.sum = ent.sum
+		r.hole = ent.hole
+		if len(r.buf) == 0 && r.err != nil {
+			// nothing but the error
+			return 0, r.err
+		}
+	}
+	nn, err = coutfn()
+	if nn < len(r.buf) {
+		// the rest of the block no longer matches its checksum
+		r.sum = nil
+	}
+	r.buf = r.buf[nn:]
+	r.baseBi += int64(nn)
+	return nn, err
+}
+
+// next returns the next entry queued for the sink.  if nothing is queued the
+// source is read and distributed until something is.
+func (r *Reader) next() (entry, error) {
+	if len(r.q) > 0 {
+		return r.pop(), nil
 	}
-	var ent entry
 	select {
-	case ent = <-r.c:
+	case ent, ok := <-r.c:
 		// channel is non-nil and has a buffer on it - read it
+		if !ok {
+			// closed while waiting
+			return entry{}, ErrClosedReader
+		}
+		return ent, nil
 	case r.mr.mtx <- struct{}{}:
 		// lock and read from the source, distribute to the sinks
-		defer r.mr.mtx.Unlock()
+	}
+	defer r.mr.mtx.Unlock()
+	for {
 		select {
-		default:
-			if r.closed {
-				return 0, r.err
-			}
-			// nothing available on channel so copy new bytes in from reader
-			// and redistribute to all other readers.  channel is empty here
-			brs := make([]byte, r.mr.blocksizeB)
-			// fill the buffer until error or blocksize
-			nn, err = r.mr.fill(brs)
-			brs = brs[:nn]
-			// brs now has the new bytes and err is any error resulting from the last read
-			// distribute the new buffer and error to all the other readers
-			r.mr.distribute(brs, err)
-			r.mr.baseBi += int64(nn)
-			ent, _ = <-r.c
-		case ent = <-r.c:
+		case ent, ok := <-r.c:
 			// protect against having items in the channel.  this should be a rare visit
+			if !ok {
+				return entry{}, ErrClosedReader
+			}
+			return ent, nil
+		default:
+		}
+		if len(r.q) > 0 {
+			return r.pop(), nil
+		}
+		if r.closed {
+			return entry{}, r.err
 		}
+		if _, ok := r.mr.cs[r.c]; !ok {
+			// detached after the last entry of its range
+			return entry{}, io.EOF
+		}
+		// nothing available on channel so copy new bytes in from reader
+		// and redistribute to all readers.  blocks outside of the range
+		// of this sink are not queued for it so go around until one is.
+		r.mr.advance(r)
 	}
-	r.baseBi = ent.i
-	r.buf = ent.bs
-	r.err = ent.err
-	nn, err = coutfn()
-	r.buf = r.buf[nn:]
-	r.baseBi += int64(nn)
-	return nn, err
+}
+
+// pop removes the first entry of the private queue.
+func (r *Reader) pop() entry {
+	ent := r.q[0]
+	r.q = r.q[1:]
+	return ent
 }
@@ -0,0 +1,54 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+// SetPacketMode treats each read of the source as one message.  Messages are
+// distributed whole as they are read, never merged with or split across
+// other messages, and are received with ReadMessage.  maxB is the size of the
+// largest message; the source is read with buffers of that size and must not
+// return messages larger than it.  maxB must be at least 1.
+//
+// SetPacketMode must be called before the first read.
+func (mr *MultiplexReader) SetPacketMode(maxB int) {
+	if maxB < 1 {
+		panic("invalid message size")
+	}
+	mr.SetLowLatency(0)
+	mr.mtx.Lock()
+	defer mr.mtx.Unlock()
+	mr.blocksizeB = maxB
+	mr.adapt = nil
+	mr.packet = true
+}
+
+// ReadMessage returns the next message from a source in packet mode.  If part
+// of the message has already been consumed by Read, the rest of it is
+// returned.  For other sources ReadMessage returns the next block.
+func (r *Reader) ReadMessage() ([]byte, error) {
+	var msg []byte
+	_, err := r.read(func() (int, error) {
+		msg = make([]byte, len(r.buf))
+		return copy(msg, r.buf), nil
+	})
+	return msg, err
+}
@@ -0,0 +1,103 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"io"
+	"testing"
+)
+
+// datagrams returns one message per read
+type datagrams struct {
+	msgs []string
+}
+
+func (d *datagrams) Read(bs []byte) (int, error) {
+	if len(d.msgs) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(bs, d.msgs[0])
+	d.msgs = d.msgs[1:]
+	return n, nil
+}
+
+func TestPacketMode(t *testing.T) {
+
+	msgs := []string{"hello", "a", "a much longer message", "bye"}
+
+	mr := NewMultiplexReader(&datagrams{append([]string{}, msgs...)})
+	mr.SetPacketMode(64)
+	r0 := mr.NewReader()
+	r1 := mr.NewReader()
+
+	for _, m := range msgs {
+		bs, err := r0.ReadMessage()
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if string(bs) != m {
+			t.Fatalf("unexpected value: %q", bs)
+		}
+	}
+	_, err := r0.ReadMessage()
+	if err != io.EOF {
+		t.Fatalf("unexpected value: %v", err)
+	}
+
+	// a message partly consumed by Read
+	bs := make([]byte, 2)
+	n, err := r1.Read(bs)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(bs[:n]) != "he" {
+		t.Fatalf("unexpected value")
+	}
+	msg, err := r1.ReadMessage()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(msg) != "llo" {
+		t.Fatalf("unexpected value: %q", msg)
+	}
+	msg, err = r1.ReadMessage()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(msg) != "a" {
+		t.Fatalf("unexpected value: %q", msg)
+	}
+
+}
+
+func TestPacketModeInvalid(t *testing.T) {
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("unexpected value")
+		}
+	}()
+	mr := NewMultiplexReader(&datagrams{msgs: []string{"a"}})
+	mr.SetPacketMode(0)
+
+}
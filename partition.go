@@ -0,0 +1,56 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import (
+	"bufio"
+	"hash/fnv"
+)
+
+// HashPartition returns a Chooser that selects a sink by hashing the key that
+// key extracts from each record.  A key is always assigned to the same
+// position among the sinks, so records with the same key reach the same
+// sink.  Records of a closed sink's partition are discarded.
+func HashPartition(key func(record []byte) []byte) Chooser {
+	return func(data []byte, sinks []*Reader) int {
+		if len(sinks) == 0 {
+			return -1
+		}
+		h := fnv.New64a()
+		h.Write(key(data))
+		return int(h.Sum64() % uint64(len(sinks)))
+	}
+}
+
+// SetPartition splits the source into records with split and delivers each
+// record to the sink chosen by hashing the key extracted from it by key.  key
+// receives the record as returned by split.  Each sink queues only its own
+// partition, and a partition whose sink falls behind holds back the source
+// once its channel is full.  All sinks must be created before the first read
+// so that the assignment of keys is stable.
+//
+// SetPartition must be called before the first read.
+func (mr *MultiplexReader) SetPartition(split bufio.SplitFunc, key func(record []byte) []byte) {
+	mr.SetSplit(split, 0)
+	mr.SetChooser(HashPartition(key))
+}
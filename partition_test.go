@@ -0,0 +1,100 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPartition(t *testing.T) {
+
+	tenants := []string{"acme", "globex", "initech", "umbrella", "hooli"}
+	lines := []string{}
+	for i := 0; i < 500; i++ {
+		lines = append(lines, fmt.Sprintf("%s,%d,%s", tenants[i%len(tenants)], i, strings.Repeat("z", i%17)))
+	}
+	src := strings.Join(lines, "\n") + "\n"
+
+	mr := NewMultiplexReaderWithSize(strings.NewReader(src), 128)
+	mr.SetPartition(bufio.ScanLines, func(record []byte) []byte {
+		return record[:bytes.IndexByte(record, ',')]
+	})
+
+	N := 3
+	rss := make([]*Reader, N)
+	for i := 0; i < N; i++ {
+		rss[i] = mr.NewReaderWithLength(4)
+	}
+
+	got := make([][]string, N)
+	wg := sync.WaitGroup{}
+	for i := 0; i < N; i++ {
+		wg.Add(1)
+		go func(j int) {
+			defer wg.Done()
+			for rss[j].Scan() {
+				got[j] = append(got[j], rss[j].Text())
+			}
+			if rss[j].Err() != nil {
+				t.Errorf("err: %v", rss[j].Err())
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	owner := map[string]int{}
+	n := 0
+	for i := 0; i < N; i++ {
+		last := -1
+		for _, l := range got[i] {
+			var tenant string
+			var k int
+			fmt.Sscanf(strings.Replace(l, ",", " ", 2), "%s %d", &tenant, &k)
+			if o, ok := owner[tenant]; ok && o != i {
+				t.Fatalf("unexpected value: %s", tenant)
+			}
+			owner[tenant] = i
+			// records of a partition stay in order
+			if k <= last {
+				t.Fatalf("unexpected value")
+			}
+			last = k
+			if l != lines[k] {
+				t.Fatalf("unexpected value: %q", l)
+			}
+			n++
+		}
+	}
+	if n != len(lines) {
+		t.Fatalf("unexpected value: %d", n)
+	}
+	if len(owner) != len(tenants) {
+		t.Fatalf("unexpected value")
+	}
+
+}
@@ -0,0 +1,79 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import "io"
+
+// NewRangeReader creates a sink that receives `length` bytes of the source
+// starting at `offset`.  A negative length reads to the end of the source.
+//
+// Blocks that end before the range are not queued for the sink.  Once the
+// range has been queued the sink detaches from the source so that it never
+// holds back the other sinks; it reports io.EOF after the last byte of the
+// range and must still be closed.  Unlike NewReader, a range sink may be
+// created after reading has started as long as the source has not yet been
+// read past offset.
+func (mr *MultiplexReader) NewRangeReader(offset, length int64) *Reader {
+	endBi := int64(-1)
+	if length >= 0 {
+		endBi = offset + length
+	}
+	return mr.newReader(default_CHANNEL_LENGTH, offset, endBi)
+}
+
+// clip returns the entry for the part of the block at baseBi that falls
+// within the range of the sink.  ok is false if nothing is to be queued.
+func (r *Reader) clip(baseBi int64, bs []byte, err error) (ent entry, ok bool) {
+	lo := baseBi
+	hi := baseBi + int64(len(bs))
+	if lo < r.offBi {
+		lo = r.offBi
+	}
+	if r.endBi >= 0 && hi >= r.endBi {
+		// the last block of the range
+		hi = r.endBi
+		err = io.EOF
+	}
+	if lo >= hi {
+		if err == nil {
+			return entry{}, false
+		}
+		// nothing in range but the error is still delivered
+		return entry{
+			i:   hi,
+			bs:  []byte{},
+			err: err,
+		}, true
+	}
+	return entry{
+		i:   lo,
+		bs:  bs[lo-baseBi : hi-baseBi],
+		err: err,
+	}, true
+}
+
+// overlaps reports whether the bytes from lo up to hi fall at least in part
+// within the range of the sink.
+func (r *Reader) overlaps(lo, hi int64) bool {
+	return hi > r.offBi && (r.endBi < 0 || lo < r.endBi)
+}
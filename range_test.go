@@ -0,0 +1,138 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRangeReader(t *testing.T) {
+
+	mr := NewMultiplexReaderWithSize(strings.NewReader(LONG_GREEK), 10)
+
+	r0 := mr.NewReader()
+	r1 := mr.NewRangeReader(6, 5)
+	r2 := mr.NewRangeReader(int64(len(LONG_GREEK)-6), -1)
+	r3 := mr.NewRangeReader(int64(len(LONG_GREEK)+10), 5)
+
+	bs, err := ioutil.ReadAll(r1)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(bs) != "ipsum" {
+		t.Fatalf("unexpected value: %q", bs)
+	}
+	if r1.baseBi != 11 {
+		t.Fatalf("unexpected value")
+	}
+	if _, ok := mr.cs[r1.c]; ok {
+		t.Fatalf("unexpected value")
+	}
+	r1.Close()
+
+	bs, err = ioutil.ReadAll(r2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(bs) != "dolor." {
+		t.Fatalf("unexpected value: %q", bs)
+	}
+	r2.Close()
+
+	// range past the end of the source
+	bs, err = ioutil.ReadAll(r3)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(bs) != 0 {
+		t.Fatalf("unexpected value")
+	}
+	r3.Close()
+
+	bs, err = ioutil.ReadAll(r0)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(bs) != LONG_GREEK {
+		t.Fatalf("unexpected value")
+	}
+	r0.Close()
+
+}
+
+func TestRangeReaderNoStall(t *testing.T) {
+
+	BN := int64(1 << 16)
+
+	src := make([]byte, BN)
+	rand.New(rand.NewSource(time.Now().Unix())).Read(src)
+
+	mr := NewMultiplexReaderWithSize(bytes.NewReader(src), 10)
+
+	// the range sink is never read after its first block while many more
+	// blocks than its channel can hold are read by the other sink
+	r0 := mr.NewRangeReader(0, 4)
+	r1 := mr.NewReaderWithLength(1)
+
+	bs := make([]byte, 4)
+	_, err := io.ReadFull(r0, bs)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	n, err := io.Copy(buf, r1)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if n != BN {
+		t.Fatalf("unexpected value")
+	}
+	if !bytes.Equal(bs, src[:4]) {
+		t.Fatalf("unexpected value")
+	}
+
+	// a late range sink can start ahead of the source
+	mr = NewMultiplexReaderWithSize(bytes.NewReader(src), 10)
+	r0 = mr.NewReader()
+	if _, err = io.ReadFull(r0, bs); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	r1 = mr.NewRangeReader(100, 20)
+	tail, err := ioutil.ReadAll(r1)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !bytes.Equal(tail, src[100:120]) {
+		t.Fatalf("unexpected value")
+	}
+	r0.Close()
+	r1.Close()
+
+}
@@ -0,0 +1,114 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import "io"
+
+const (
+	default_PARALLEL = 1 << 3
+)
+
+// NewMultiplexReaderAt creates a new source reader for the first `size` bytes
+// of ra.  Blocks ahead of the sinks are read with concurrent ReadAt calls and
+// distributed in order.
+func NewMultiplexReaderAt(ra io.ReaderAt, size int64) *MultiplexReader {
+	return NewMultiplexReaderAtWithSize(ra, size, default_BLOCK_SIZE_B, default_PARALLEL)
+}
+
+// NewMultiplexReaderAtWithSize creates a new source reader for ra that buffers
+// in blocks of `size` bytes with up to `parallel` ReadAt calls outstanding.
+// No more than `parallel` blocks are held ahead of the sinks.
+func NewMultiplexReaderAtWithSize(ra io.ReaderAt, size int64, sizeB, parallel int) *MultiplexReader {
+	if parallel < 1 {
+		parallel = 1
+	}
+	return NewMultiplexReaderWithSize(&prefetcher{
+		ra:       ra,
+		size:     size,
+		parallel: parallel,
+	}, sizeB)
+}
+
+// prefetcher reads blocks of a ReaderAt ahead of the sinks.  offBi is the
+// offset of the next block to request.
+type prefetcher struct {
+	ra       io.ReaderAt
+	size     int64
+	offBi    int64
+	parallel int
+	q        []<-chan readResult
+	err      error
+}
+
+// Read fulfills the io.Reader interface for use outside of a MultiplexReader.
+func (p *prefetcher) Read(bs []byte) (int, error) {
+	brs, err := p.readBlock(len(bs))
+	return copy(bs, brs), err
+}
+
+func (p *prefetcher) readBlock(sizeB int) ([]byte, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	for len(p.q) < p.parallel && p.offBi < p.size {
+		n := int64(sizeB)
+		if p.size-p.offBi < n {
+			n = p.size - p.offBi
+		}
+		p.q = append(p.q, goReadAt(p.ra, p.offBi, int(n)))
+		p.offBi += n
+	}
+	if len(p.q) == 0 {
+		p.err = io.EOF
+		return nil, p.err
+	}
+	res := <-p.q[0]
+	p.q = p.q[1:]
+	if res.err != nil {
+		// blocks already requested are abandoned
+		p.err = res.err
+		p.q = nil
+		return res.bs, p.err
+	}
+	if len(p.q) == 0 && p.offBi >= p.size {
+		p.err = io.EOF
+	}
+	return res.bs, p.err
+}
+
+// goReadAt reads n bytes at offset off of ra in a new goroutine.
+func goReadAt(ra io.ReaderAt, off int64, n int) <-chan readResult {
+	c := make(chan readResult, 1)
+	go func() {
+		bs := make([]byte, n)
+		m, err := ra.ReadAt(bs, off)
+		if m == n {
+			// a full read at the end of the source may report io.EOF
+			err = nil
+		} else if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		c <- readResult{bs[:m], err}
+	}()
+	return c
+}
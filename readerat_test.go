@@ -0,0 +1,129 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+// latent delays ReadAt calls and records the most calls outstanding at once
+type latent struct {
+	io.ReaderAt
+	mtx sync.Mutex
+	n   int
+	max int
+}
+
+func (l *latent) ReadAt(bs []byte, off int64) (int, error) {
+	l.mtx.Lock()
+	l.n++
+	if l.n > l.max {
+		l.max = l.n
+	}
+	l.mtx.Unlock()
+	time.Sleep(time.Millisecond * 5)
+	l.mtx.Lock()
+	l.n--
+	l.mtx.Unlock()
+	return l.ReaderAt.ReadAt(bs, off)
+}
+
+func TestReaderAtParallel(t *testing.T) {
+
+	BN := 1<<16 + 7
+
+	src := make([]byte, BN)
+	rand.New(rand.NewSource(time.Now().Unix())).Read(src)
+	ra := &latent{ReaderAt: bytes.NewReader(src)}
+
+	mr := NewMultiplexReaderAtWithSize(ra, int64(BN), 1<<10, 4)
+	r0 := mr.NewReader()
+	r1 := mr.NewReader()
+
+	wg := sync.WaitGroup{}
+	var bs1 []byte
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		bs1, _ = ioutil.ReadAll(r1)
+	}()
+	bs0, err := ioutil.ReadAll(r0)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	wg.Wait()
+
+	if !bytes.Equal(bs0, src) {
+		t.Fatalf("unexpected value")
+	}
+	if !bytes.Equal(bs1, src) {
+		t.Fatalf("unexpected value")
+	}
+	if ra.max < 2 || ra.max > 4 {
+		t.Fatalf("unexpected value: %d", ra.max)
+	}
+
+}
+
+type errReaderAt struct {
+	io.ReaderAt
+	offBi int64
+}
+
+func (r *errReaderAt) ReadAt(bs []byte, off int64) (int, error) {
+	if off >= r.offBi {
+		return 0, errors.New("testing")
+	}
+	return r.ReaderAt.ReadAt(bs, off)
+}
+
+func TestReaderAtError(t *testing.T) {
+
+	src := []byte(LONG_GREEK)
+	mr := NewMultiplexReaderAtWithSize(&errReaderAt{bytes.NewReader(src), 100}, int64(len(src)), 10, 3)
+	r0 := mr.NewReader()
+
+	bs, err := ioutil.ReadAll(r0)
+	if err == nil || err.Error() != "testing" {
+		t.Fatalf("unexpected value: %v", err)
+	}
+	if !bytes.Equal(bs, src[:100]) {
+		t.Fatalf("unexpected value")
+	}
+
+	// source shorter than its stated size
+	mr = NewMultiplexReaderAtWithSize(bytes.NewReader(src), int64(len(src)+5), 10, 3)
+	r0 = mr.NewReader()
+	_, err = ioutil.ReadAll(r0)
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("unexpected value: %v", err)
+	}
+
+}
@@ -0,0 +1,173 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import (
+	"bufio"
+	"io"
+)
+
+// SetSplit frames the source into records with split, as bufio.Scanner does,
+// and cuts blocks only on record boundaries so that no record is torn across
+// blocks.  A block grows past the block size to hold a record larger than it,
+// up to maxB bytes, beyond which bufio.ErrTooLong is distributed.  A maxB
+// of zero or less does not limit records.  Sinks receive whole records with
+// Scan.
+//
+// SetSplit must be called before the first read.
+func (mr *MultiplexReader) SetSplit(split bufio.SplitFunc, maxB int) {
+	mr.mtx.Lock()
+	defer mr.mtx.Unlock()
+	if mr.baseBi > 0 {
+		panic("late start")
+	}
+	mr.split = split
+	mr.maxRecordB = maxB
+}
+
+// nextRecords reads blocks until they hold at least one whole record.  bytes
+// past the last record boundary are carried into the next block.
+func (mr *MultiplexReader) nextRecords() ([]byte, error) {
+	bs := mr.carry
+	mr.carry = nil
+	for {
+		brs, err := mr.nextBlock()
+		if len(bs) == 0 {
+			bs = brs
+		} else {
+			bs = append(bs, brs...)
+		}
+		if err != nil {
+			// the end of the stream ends the last record
+			return bs, err
+		}
+		k, err := boundary(mr.split, bs)
+		if err != nil {
+			return bs[:k], err
+		}
+		if k > 0 {
+			mr.carry = append([]byte(nil), bs[k:]...)
+			return bs[:k], nil
+		}
+		if mr.maxRecordB > 0 && len(bs) >= mr.maxRecordB {
+			return nil, bufio.ErrTooLong
+		}
+	}
+}
+
+// boundary returns the offset just past the last whole record in bs.
+func boundary(split bufio.SplitFunc, bs []byte) (int, error) {
+	k := 0
+	for k < len(bs) {
+		adv, _, err := split(bs[k:], false)
+		if err != nil {
+			return k, err
+		}
+		if adv < 0 {
+			return k, bufio.ErrNegativeAdvance
+		}
+		if adv > len(bs)-k {
+			return k, bufio.ErrAdvanceTooFar
+		}
+		if adv == 0 {
+			break
+		}
+		k += adv
+	}
+	return k, nil
+}
+
+// Scan advances the sink to the next record of a source framed by SetSplit.
+// The record is then available through Bytes or Text.  Scan returns false at
+// the end of the stream or on error, after which Err reports the error.
+func (r *Reader) Scan() bool {
+	if r.mr.split == nil {
+		panic("no split function")
+	}
+	if !r.mr.hold() {
+		r.tokErr = ErrClosedReader
+		return false
+	}
+	defer r.mr.unhold()
+	r.tok = nil
+	for r.tokErr == nil {
+		if len(r.buf) == 0 {
+			// make the next block current
+			_, err := r.read(func() (int, error) {
+				return 0, nil
+			})
+			if err != nil {
+				r.tokErr = err
+			}
+			continue
+		}
+		// blocks end on record boundaries so each is split as if at the
+		// end of the stream
+		adv, tok, err := r.mr.split(r.buf, true)
+		switch {
+		case err != nil && err != bufio.ErrFinalToken:
+			r.tokErr = err
+			return false
+		case adv < 0:
+			r.tokErr = bufio.ErrNegativeAdvance
+			return false
+		case adv > len(r.buf):
+			r.tokErr = bufio.ErrAdvanceTooFar
+			return false
+		case adv == 0 && tok == nil:
+			r.tokErr = io.ErrNoProgress
+			return false
+		}
+		r.buf = r.buf[adv:]
+		r.baseBi += int64(adv)
+		if err == bufio.ErrFinalToken {
+			r.tokErr = err
+			r.tok = tok
+			return tok != nil
+		}
+		if tok != nil {
+			r.tok = tok
+			return true
+		}
+	}
+	return false
+}
+
+// Bytes returns the record found by the last call to Scan.  The record is
+// shared with the other sinks and must not be modified.
+func (r *Reader) Bytes() []byte {
+	return r.tok
+}
+
+// Text returns the record found by the last call to Scan as a string.
+func (r *Reader) Text() string {
+	return string(r.tok)
+}
+
+// Err returns the error that ended Scan, or nil at the end of the stream.
+func (r *Reader) Err() error {
+	if r.tokErr == io.EOF || r.tokErr == bufio.ErrFinalToken {
+		return nil
+	}
+	return r.tokErr
+}
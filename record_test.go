@@ -0,0 +1,103 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRecordMode(t *testing.T) {
+
+	lines := []string{}
+	for i := 0; i < 100; i++ {
+		lines = append(lines, fmt.Sprintf(`{"id": %d, "name": "%s"}`, i, strings.Repeat("x", i%40)))
+	}
+	src := strings.Join(lines, "\n") + "\n"
+
+	mr := NewMultiplexReaderWithSize(strings.NewReader(src), 16)
+	mr.SetSplit(bufio.ScanLines, 0)
+	r0 := mr.NewReader()
+	r1 := mr.NewReader()
+
+	i := 0
+	for r0.Scan() {
+		if r0.Text() != lines[i] {
+			t.Fatalf("unexpected value: %q", r0.Text())
+		}
+		i++
+	}
+	if r0.Err() != nil {
+		t.Fatalf("err: %v", r0.Err())
+	}
+	if i != len(lines) {
+		t.Fatalf("unexpected value: %d", i)
+	}
+
+	// every block read ends on a record boundary
+	buf := &bytes.Buffer{}
+	for {
+		bs, err := r1.ReadMessage()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if bs[len(bs)-1] != '\n' {
+			t.Fatalf("unexpected value: %q", bs)
+		}
+		buf.Write(bs)
+	}
+	if buf.String() != src {
+		t.Fatalf("unexpected value")
+	}
+
+}
+
+func TestRecordModeTooLong(t *testing.T) {
+
+	src := "short\n" + strings.Repeat("y", 100) + "\nshort\n"
+
+	mr := NewMultiplexReaderWithSize(strings.NewReader(src), 16)
+	mr.SetSplit(bufio.ScanLines, 64)
+	r0 := mr.NewReader()
+
+	if !r0.Scan() {
+		t.Fatalf("unexpected value")
+	}
+	if r0.Text() != "short" {
+		t.Fatalf("unexpected value")
+	}
+	if r0.Scan() {
+		t.Fatalf("unexpected value")
+	}
+	if r0.Err() != bufio.ErrTooLong {
+		t.Fatalf("unexpected value: %v", r0.Err())
+	}
+
+}
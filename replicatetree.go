@@ -0,0 +1,304 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import (
+	"errors"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+const (
+	default_TREE_CONCURRENCY = 1 << 2
+)
+
+var ErrIncomplete = errors.New("replication incomplete")
+
+// TreeOptions configures the replication of trees.
+type TreeOptions struct {
+	CopyOptions
+	// Concurrency is the most files replicated at once.  Zero or less uses
+	// a default of 4.
+	Concurrency int
+	// Include limits the files and symlinks replicated to those matching
+	// one of its patterns, unless it is empty.  Exclude skips files,
+	// symlinks and whole directories matching one of its patterns.  A
+	// pattern matches an entry if it matches, as by path.Match, either its
+	// slash separated path within the tree or its base name.
+	Include []string
+	Exclude []string
+}
+
+// TreeReport summarizes the replication of a tree.
+type TreeReport struct {
+	// Files, Dirs and Symlinks count the entries replicated to at least one
+	// destination.
+	Files    int
+	Dirs     int
+	Symlinks int
+	// Bytes counts the bytes of the files replicated.
+	Bytes    int64
+	Failures []TreeFailure
+}
+
+// TreeFailure reports an entry of a tree that was not replicated to a
+// destination.
+type TreeFailure struct {
+	// Path is the slash separated path of the entry within the tree.
+	Path string
+	// Dest is the destination root, or empty if the entry failed for every
+	// destination.
+	Dest string
+	Err  error
+}
+
+// ReplicateTree replicates the tree src to each of the directories dsts.  See
+// ReplicateTreeWithOptions.
+func ReplicateTree(src fs.FS, dsts ...string) (*TreeReport, error) {
+	return ReplicateTreeWithOptions(src, TreeOptions{CopyOptions: CopyOptions{Sync: true}}, dsts...)
+}
+
+// ReplicateTreeWithOptions replicates the tree src to each of the directories
+// dsts.  Directories are created, symlinks recreated and each regular file is
+// read once and copied to every destination as by CopyFileWithOptions.
+// Symlinks are read with the ReadLink method of src, and are reported as
+// failures where it has none.  Other files are skipped.  Entries are replicated to the destinations they
+// can be and the others are listed in the report, in which case
+// ErrIncomplete is returned along with it.
+func ReplicateTreeWithOptions(src fs.FS, opts TreeOptions, dsts ...string) (*TreeReport, error) {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = default_TREE_CONCURRENCY
+	}
+	t := &treeReplicator{
+		src:  src,
+		opts: opts,
+		dsts: dsts,
+		rpt:  &TreeReport{},
+		sem:  make(chan struct{}, opts.Concurrency),
+	}
+	fs.WalkDir(src, ".", t.visit)
+	t.wg.Wait()
+	t.finishDirs()
+	if len(t.rpt.Failures) > 0 {
+		return t.rpt, ErrIncomplete
+	}
+	return t.rpt, nil
+}
+
+type treeReplicator struct {
+	src  fs.FS
+	opts TreeOptions
+	dsts []string
+	sem  chan struct{}
+	wg   sync.WaitGroup
+	mtx  sync.Mutex
+	rpt  *TreeReport
+	// dirs holds the directories created and their modes, to be applied
+	// once their contents are written
+	dirs  []string
+	modes []fs.FileMode
+}
+
+func (t *treeReplicator) visit(p string, d fs.DirEntry, err error) error {
+	if err != nil {
+		t.fail(p, "", err)
+		if d != nil && d.IsDir() {
+			return fs.SkipDir
+		}
+		return nil
+	}
+	if p != "." && matchAny(t.opts.Exclude, p) {
+		if d.IsDir() {
+			return fs.SkipDir
+		}
+		return nil
+	}
+	switch {
+	case d.IsDir():
+		return t.mkdir(p, d)
+	case len(t.opts.Include) > 0 && !matchAny(t.opts.Include, p):
+	case d.Type()&fs.ModeSymlink != 0:
+		t.symlink(p)
+	case d.Type().IsRegular():
+		t.wg.Add(1)
+		t.sem <- struct{}{}
+		go func() {
+			defer t.wg.Done()
+			defer func() { <-t.sem }()
+			t.copy(p)
+		}()
+	}
+	return nil
+}
+
+// mkdir creates the directory p in every destination.  Directories are
+// writable until their contents are written.
+func (t *treeReplicator) mkdir(p string, d fs.DirEntry) error {
+	fi, err := d.Info()
+	if err != nil {
+		t.fail(p, "", err)
+		return fs.SkipDir
+	}
+	ok := 0
+	for _, dst := range t.dsts {
+		err := os.MkdirAll(t.join(dst, p), fi.Mode().Perm()|0700)
+		if err != nil {
+			t.fail(p, dst, err)
+			continue
+		}
+		ok++
+	}
+	if ok == 0 {
+		return fs.SkipDir
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.rpt.Dirs++
+	t.dirs = append(t.dirs, p)
+	t.modes = append(t.modes, fi.Mode().Perm())
+	return nil
+}
+
+// finishDirs gives the directories the modes of the source, deepest first.
+func (t *treeReplicator) finishDirs() {
+	for i := len(t.dirs) - 1; i >= 0; i-- {
+		for _, dst := range t.dsts {
+			name := t.join(dst, t.dirs[i])
+			if _, err := os.Stat(name); err != nil {
+				// never created, already reported
+				continue
+			}
+			if err := os.Chmod(name, t.modes[i]); err != nil {
+				t.fail(t.dirs[i], dst, err)
+			}
+		}
+	}
+}
+
+// readLinkFS is a file system that reads symlinks, such as the one returned
+// by os.DirFS.
+type readLinkFS interface {
+	ReadLink(name string) (string, error)
+}
+
+// symlink recreates the symlink p in every destination, replacing what is
+// there.
+func (t *treeReplicator) symlink(p string) {
+	lfs, isLink := t.src.(readLinkFS)
+	if !isLink {
+		t.fail(p, "", &fs.PathError{Op: "readlink", Path: p, Err: errors.New("readlink unsupported")})
+		return
+	}
+	target, err := lfs.ReadLink(p)
+	if err != nil {
+		t.fail(p, "", err)
+		return
+	}
+	ok := 0
+	for _, dst := range t.dsts {
+		name := t.join(dst, p)
+		tmp := filepath.Join(filepath.Dir(name), "."+filepath.Base(name)+".tmp"+strconv.FormatUint(uint64(rand.Uint32()), 10))
+		err := os.Symlink(target, tmp)
+		if err == nil {
+			if err = os.Rename(tmp, name); err != nil {
+				os.Remove(tmp)
+			}
+		}
+		if err != nil {
+			t.fail(p, dst, err)
+			continue
+		}
+		ok++
+	}
+	if ok > 0 {
+		t.mtx.Lock()
+		defer t.mtx.Unlock()
+		t.rpt.Symlinks++
+	}
+}
+
+// copy replicates the regular file p to every destination.
+func (t *treeReplicator) copy(p string) {
+	f, err := t.src.Open(p)
+	if err != nil {
+		t.fail(p, "", err)
+		return
+	}
+	defer f.Close()
+	names := make([]string, len(t.dsts))
+	for i, dst := range t.dsts {
+		names[i] = t.join(dst, p)
+	}
+	n, err := copyFile(p, f, t.opts.CopyOptions, names)
+	cerr, ok := err.(*CopyError)
+	if err != nil && !ok {
+		t.fail(p, "", err)
+		return
+	}
+	copied := false
+	for i, dst := range t.dsts {
+		if ok && cerr.Errs[i] != nil {
+			t.fail(p, dst, cerr.Errs[i])
+			continue
+		}
+		copied = true
+	}
+	if copied {
+		t.mtx.Lock()
+		defer t.mtx.Unlock()
+		t.rpt.Files++
+		t.rpt.Bytes += n
+	}
+}
+
+func (t *treeReplicator) join(dst, p string) string {
+	return filepath.Join(dst, filepath.FromSlash(p))
+}
+
+func (t *treeReplicator) fail(p, dst string, err error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.rpt.Failures = append(t.rpt.Failures, TreeFailure{
+		Path: p,
+		Dest: dst,
+		Err:  err,
+	})
+}
+
+// matchAny reports whether any of the patterns matches p or its base name.
+func matchAny(patterns []string, p string) bool {
+	for _, pat := range patterns {
+		if ok, _ := path.Match(pat, p); ok {
+			return true
+		}
+		if ok, _ := path.Match(pat, path.Base(p)); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func sourceTree(t *testing.T) string {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"a/b.txt":   SHORT_GREEK,
+		"a/c.log":   "log",
+		"d/e/f.txt": LONG_GREEK,
+		"skip/g":    "skipped",
+	} {
+		name = filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(name), 0755)
+		if err := ioutil.WriteFile(name, []byte(content), 0640); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	os.Chmod(filepath.Join(root, "a"), 0750)
+	if runtime.GOOS != "windows" {
+		os.Symlink("a/b.txt", filepath.Join(root, "link"))
+	}
+	return root
+}
+
+func TestReplicateTree(t *testing.T) {
+
+	src := sourceTree(t)
+	dsts := []string{t.TempDir(), t.TempDir()}
+
+	rpt, err := ReplicateTreeWithOptions(os.DirFS(src), TreeOptions{
+		Concurrency: 2,
+		Exclude:     []string{"skip", "*.log"},
+	}, dsts...)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, rpt.Failures)
+	}
+	if rpt.Files != 2 || rpt.Dirs != 4 || rpt.Bytes != int64(len(SHORT_GREEK)+len(LONG_GREEK)) {
+		t.Fatalf("unexpected value: %+v", rpt)
+	}
+
+	for _, dst := range dsts {
+		for name, content := range map[string]string{
+			"a/b.txt":   SHORT_GREEK,
+			"d/e/f.txt": LONG_GREEK,
+		} {
+			bs, err := ioutil.ReadFile(filepath.Join(dst, filepath.FromSlash(name)))
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			if string(bs) != content {
+				t.Fatalf("unexpected value")
+			}
+		}
+		for _, name := range []string{"a/c.log", "skip"} {
+			if _, err := os.Lstat(filepath.Join(dst, filepath.FromSlash(name))); !os.IsNotExist(err) {
+				t.Fatalf("unexpected value: %s", name)
+			}
+		}
+		if runtime.GOOS == "windows" {
+			continue
+		}
+		fi, err := os.Stat(filepath.Join(dst, "a"))
+		if err != nil || fi.Mode().Perm() != 0750 {
+			t.Fatalf("unexpected value")
+		}
+		target, err := os.Readlink(filepath.Join(dst, "link"))
+		if err != nil || target != "a/b.txt" {
+			t.Fatalf("unexpected value: %v", err)
+		}
+	}
+	if runtime.GOOS != "windows" && rpt.Symlinks != 1 {
+		t.Fatalf("unexpected value")
+	}
+
+}
+
+func TestReplicateTreeFailures(t *testing.T) {
+
+	src := sourceTree(t)
+	dst := t.TempDir()
+	// a file in the way of a destination directory
+	bad := filepath.Join(t.TempDir(), "file")
+	ioutil.WriteFile(bad, nil, 0600)
+
+	rpt, err := ReplicateTreeWithOptions(os.DirFS(src), TreeOptions{
+		Include: []string{"*.txt"},
+	}, dst, bad)
+	if err != ErrIncomplete {
+		t.Fatalf("unexpected value: %v", err)
+	}
+	// nothing can be replicated to bad and the other destination is
+	// replicated in full
+	if rpt.Files != 2 || rpt.Symlinks != 0 {
+		t.Fatalf("unexpected value: %+v", rpt)
+	}
+	for _, f := range rpt.Failures {
+		if f.Dest != bad {
+			t.Fatalf("unexpected value: %+v", f)
+		}
+	}
+	if len(rpt.Failures) == 0 {
+		t.Fatalf("unexpected value")
+	}
+	if _, err := os.Stat(filepath.Join(dst, "a", "b.txt")); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+}
+
+func TestReplicateTreeNoReadLink(t *testing.T) {
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	src := sourceTree(t)
+	dst := t.TempDir()
+
+	// a file system that cannot read the symlink reports it
+	rpt, err := ReplicateTreeWithOptions(struct{ fs.FS }{os.DirFS(src)}, TreeOptions{
+		Exclude: []string{"skip", "*.log"},
+	}, dst)
+	if err != ErrIncomplete {
+		t.Fatalf("unexpected value: %v", err)
+	}
+	if rpt.Files != 2 || rpt.Symlinks != 0 || len(rpt.Failures) != 1 || rpt.Failures[0].Path != "link" {
+		t.Fatalf("unexpected value: %+v", rpt)
+	}
+
+}
@@ -0,0 +1,244 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+)
+
+const (
+	default_RESUME_BLOCK_B = 1 << 20
+)
+
+// ResumeFile completes copies of the regular file src at each of dsts and
+// syncs them to disk.  See ResumeFileWithOptions.
+func ResumeFile(src string, dsts ...string) (int64, error) {
+	return ResumeFileWithOptions(src, CopyOptions{Sync: true}, dsts...)
+}
+
+// ResumeFileWithOptions completes copies of the regular file src at each of
+// dsts, such as those left by an interrupted copy.  Each destination is
+// compared with src by hashing blocks of 1 MiB, and is written from the first
+// block that does not match.  src is read once from the earliest of those
+// blocks, and only the destinations behind are written while it catches up
+// with the others.  Destinations that do not exist are created, and those
+// longer than src are truncated.  Unlike CopyFile, destinations are written
+// in place.  With Verify, each destination is read back in full once written
+// and those that do not match the hashes of the blocks of src are reported
+// with a *VerifyError.
+//
+// ResumeFileWithOptions returns the number of bytes read from src to write
+// the destinations.  Destinations that fail are reported in a *CopyError.
+func ResumeFileWithOptions(src string, opts CopyOptions, dsts ...string) (int64, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if !fi.Mode().IsRegular() {
+		return 0, &os.PathError{Op: "resume", Path: src, Err: errors.New("not a regular file")}
+	}
+	p := &prefix{
+		src:    f,
+		size:   fi.Size(),
+		blockB: default_RESUME_BLOCK_B,
+	}
+
+	ds := make([]*os.File, len(dsts))
+	offs := make([]int64, len(dsts))
+	errs := make([]error, len(dsts))
+	minOff := p.size
+	for i, dst := range dsts {
+		d, err := os.OpenFile(dst, os.O_RDWR|os.O_CREATE, fi.Mode().Perm())
+		if err == nil {
+			offs[i], err = p.match(d)
+		}
+		if err == nil {
+			_, err = d.Seek(offs[i], io.SeekStart)
+		}
+		if err != nil {
+			if d != nil {
+				d.Close()
+			}
+			errs[i] = err
+			continue
+		}
+		ds[i] = d
+		if offs[i] < minOff {
+			minOff = offs[i]
+		}
+	}
+
+	var nn int64
+	if _, err := f.Seek(minOff, io.SeekStart); err != nil {
+		for i, d := range ds {
+			if d != nil {
+				d.Close()
+				errs[i] = err
+			}
+		}
+	} else {
+		ws := []io.Writer{}
+		idx := []int{}
+		for i, d := range ds {
+			if d != nil {
+				ws = append(ws, d)
+				idx = append(idx, i)
+			}
+		}
+		werrs := []error{}
+		var t *MerkleTree
+		terr := ErrDigestUnavailable
+		if len(ws) > 0 {
+			// the source is only mapped once there are sinks to release it
+			nn = p.size - minOff
+			mr := NewMappedMultiplexReader(f)
+			if opts.Verify {
+				mr.SetBlockChecksums(DigestSHA256)
+			}
+			rs := make([]*Reader, len(ws))
+			for j, i := range idx {
+				rs[j] = mr.NewRangeReader(offs[i]-minOff, -1)
+			}
+			_, werrs = copyReaders(rs, ws)
+			if opts.Verify {
+				var rest *MerkleTree
+				if rest, terr = mr.MerkleTree(); terr == nil {
+					t = p.tree(minOff, rest)
+				}
+			}
+		}
+		for j, i := range idx {
+			err := werrs[j]
+			if err == nil {
+				err = ds[i].Truncate(p.size)
+			}
+			if err == nil && opts.Sync {
+				err = ds[i].Sync()
+			}
+			if cerr := ds[i].Close(); err == nil {
+				err = cerr
+			}
+			if err == nil && opts.Verify {
+				err = terr
+				if err == nil {
+					err = verifyFile(t, dsts[i], dsts[i])
+				}
+			}
+			errs[i] = err
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nn, &CopyError{Errs: errs}
+		}
+	}
+	return nn, nil
+}
+
+// prefix compares destinations with the blocks of a source.  sums holds the
+// hashes of the leading blocks of the source compared so far.
+type prefix struct {
+	src    io.ReaderAt
+	size   int64
+	blockB int
+	sums   [][]byte
+	buf    []byte
+}
+
+// match returns the offset of the first block of d that does not match the
+// source.  Blocks that d holds only part of do not match.
+func (p *prefix) match(d io.ReaderAt) (int64, error) {
+	var off int64
+	for i := 0; off < p.size; i++ {
+		l := int64(p.blockB)
+		if p.size-off < l {
+			l = p.size - off
+		}
+		sum, err := p.sum(i, off, int(l))
+		if err != nil {
+			return 0, err
+		}
+		n, err := d.ReadAt(p.buf[:l], off)
+		if int64(n) < l {
+			if err == io.EOF {
+				break
+			}
+			return 0, err
+		}
+		if !bytes.Equal(DigestSHA256.Sum(p.buf[:l]), sum) {
+			break
+		}
+		off += l
+	}
+	return off, nil
+}
+
+// tree returns the MerkleTree of the whole source from the blocks compared
+// below off and the tree rest of the source from off.
+func (p *prefix) tree(off int64, rest *MerkleTree) *MerkleTree {
+	t := &MerkleTree{Digest: DigestSHA256}
+	var o int64
+	for i := 0; o < off; i++ {
+		l := int64(p.blockB)
+		if off-o < l {
+			l = off - o
+		}
+		t.Blocks = append(t.Blocks, MerkleLeaf{
+			Offset: o,
+			Length: int(l),
+			Sum:    p.sums[i],
+		})
+		o += l
+	}
+	for _, b := range rest.Blocks {
+		b.Offset += off
+		t.Blocks = append(t.Blocks, b)
+	}
+	return t
+}
+
+// sum returns the hash of block i of the source, l bytes at off.
+func (p *prefix) sum(i int, off int64, l int) ([]byte, error) {
+	if p.buf == nil {
+		p.buf = make([]byte, p.blockB)
+	}
+	if i < len(p.sums) {
+		return p.sums[i], nil
+	}
+	if n, err := p.src.ReadAt(p.buf[:l], off); n < l {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	p.sums = append(p.sums, DigestSHA256.Sum(p.buf[:l]))
+	return p.sums[i], nil
+}
@@ -0,0 +1,214 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResumeFile(t *testing.T) {
+
+	dir := t.TempDir()
+	img := make([]byte, 3<<20+12345)
+	rand.New(rand.NewSource(1)).Read(img)
+	src := filepath.Join(dir, "src")
+	if err := ioutil.WriteFile(src, img, 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// interrupted at different points
+	dsts := []string{
+		filepath.Join(dir, "a"),
+		filepath.Join(dir, "b"),
+		filepath.Join(dir, "c"),
+	}
+	ioutil.WriteFile(dsts[0], img[:2<<20+100], 0600)
+	ioutil.WriteFile(dsts[1], img[:1<<20+5], 0600)
+	ioutil.WriteFile(dsts[2], append(append([]byte{}, img...), "trailing"...), 0600)
+
+	n, err := ResumeFile(src, dsts...)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	// read from the block b was interrupted in
+	if n != int64(len(img)-1<<20) {
+		t.Fatalf("unexpected value: %d", n)
+	}
+	for _, dst := range dsts {
+		bs, _ := ioutil.ReadFile(dst)
+		if !bytes.Equal(bs, img) {
+			t.Fatalf("unexpected value")
+		}
+	}
+
+	// a corrupt block is rewritten along with what follows it, and a
+	// missing destination is written in full
+	bs, _ := ioutil.ReadFile(dsts[0])
+	bs[2<<20+7] ^= 1
+	ioutil.WriteFile(dsts[0], bs, 0600)
+	dsts = append(dsts, filepath.Join(dir, "d"))
+	n, err = ResumeFile(src, dsts...)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if n != int64(len(img)) {
+		t.Fatalf("unexpected value: %d", n)
+	}
+	for _, dst := range dsts {
+		bs, _ := ioutil.ReadFile(dst)
+		if !bytes.Equal(bs, img) {
+			t.Fatalf("unexpected value")
+		}
+	}
+
+	// complete destinations read nothing
+	n, err = ResumeFile(src, dsts...)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("unexpected value: %d", n)
+	}
+
+}
+
+func TestResumeFileVerify(t *testing.T) {
+
+	dir := t.TempDir()
+	img := make([]byte, 3<<20+12345)
+	rand.New(rand.NewSource(1)).Read(img)
+	src := filepath.Join(dir, "src")
+	if err := ioutil.WriteFile(src, img, 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	dsts := []string{
+		filepath.Join(dir, "a"),
+		filepath.Join(dir, "b"),
+		filepath.Join(dir, "c"),
+	}
+	ioutil.WriteFile(dsts[0], img[:2<<20+100], 0600)
+	ioutil.WriteFile(dsts[1], img[:1<<20+5], 0600)
+
+	n, err := ResumeFileWithOptions(src, CopyOptions{Verify: true}, dsts...)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if n != int64(len(img)) {
+		t.Fatalf("unexpected value: %d", n)
+	}
+
+	// the tree of a resumed copy covers the compared blocks and the rest
+	f, err := os.Open(src)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+	p := &prefix{src: f, size: int64(len(img)), blockB: 1 << 20}
+	off, err := p.match(bytes.NewReader(img[:2<<20+100]))
+	if err != nil || off != 2<<20 {
+		t.Fatalf("unexpected value: %d %v", off, err)
+	}
+	mr := NewMultiplexReaderWithSize(bytes.NewReader(img[off:]), 1<<16)
+	mr.SetBlockChecksums(DigestSHA256)
+	if _, err := mr.copyTo([]io.Writer{ioutil.Discard}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	rest, err := mr.MerkleTree()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	tree := p.tree(off, rest)
+	if err := VerifyFiles(tree, dsts...); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	bs := append([]byte{}, img...)
+	bs[1<<20+3] ^= 1
+	bs[2<<20+1<<16+3] ^= 1
+	ioutil.WriteFile(dsts[2], bs, 0600)
+	err = VerifyFiles(tree, dsts[2])
+	cerr, ok := err.(*CopyError)
+	if !ok {
+		t.Fatalf("unexpected value: %v", err)
+	}
+	verr, ok := cerr.Errs[0].(*VerifyError)
+	if !ok || len(verr.Blocks) != 2 || verr.Blocks[0] != 1 || verr.Blocks[1] != 3 || verr.Offset != 1<<20 {
+		t.Fatalf("unexpected value: %v", cerr.Errs[0])
+	}
+
+}
+
+func TestResumeFileUnmapped(t *testing.T) {
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := ioutil.WriteFile(src, []byte(LONG_GREEK), 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// no destination opens so the source is never mapped
+	for i := 0; i < 5; i++ {
+		n, err := ResumeFile(src, filepath.Join(dir, "missing", "dst"))
+		if _, ok := err.(*CopyError); !ok || n != 0 {
+			t.Fatalf("unexpected value: %v", err)
+		}
+	}
+	if n := mappings(src); n > 0 {
+		t.Fatalf("unexpected value: %d", n)
+	}
+
+}
+
+func TestPrefixMatch(t *testing.T) {
+
+	p := &prefix{
+		src:    bytes.NewReader([]byte(LONG_GREEK)),
+		size:   int64(len(LONG_GREEK)),
+		blockB: 100,
+	}
+	bad := []byte(LONG_GREEK)
+	bad[250] ^= 1
+	for _, c := range []struct {
+		dst  []byte
+		want int64
+	}{
+		{[]byte(LONG_GREEK), int64(len(LONG_GREEK))},
+		{[]byte(LONG_GREEK[:299]), 200},
+		{bad, 200},
+		{nil, 0},
+	} {
+		off, err := p.match(bytes.NewReader(c.dst))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if off != c.want {
+			t.Fatalf("unexpected value: %d", off)
+		}
+	}
+
+}
@@ -0,0 +1,311 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// OpenFunc opens a source positioned `offset` bytes from the start of the
+// stream, for example with an HTTP Range request or by reopening and seeking
+// a file.
+type OpenFunc func(offset int64) (io.ReadCloser, error)
+
+// RetryPolicy reports whether a failed source should be reopened.  attempt
+// counts the consecutive failures, starting at 1, and err is the failure.
+type RetryPolicy func(attempt int, err error) bool
+
+// Retry returns a RetryPolicy that allows up to n consecutive reopens,
+// sleeping `delay` before the first and doubling the delay for each one
+// after it.
+func Retry(n int, delay time.Duration) RetryPolicy {
+	return func(attempt int, err error) bool {
+		if attempt > n {
+			return false
+		}
+		time.Sleep(delay << uint(attempt-1))
+		return true
+	}
+}
+
+// ErrSourceMismatch is reported when a reopened source does not match the bytes
+// already read from the stream.
+var ErrSourceMismatch = errors.New("multio: source mismatch")
+
+// Failover configures a source read from equivalent replicas of the same
+// stream.
+type Failover struct {
+	// Policy reports whether to reopen after a failure.  A nil Policy never
+	// reopens.
+	Policy RetryPolicy
+	// Timeout is the longest a read may take before the next source is
+	// opened at the same offset and read in parallel.  The first of the two
+	// to return data is used and the other is closed.  Zero disables
+	// hedging.
+	Timeout time.Duration
+	// OverlapB is the number of bytes before the current offset that are read
+	// again from a reopened source and compared with the bytes already read.
+	// A difference is reported as ErrSourceMismatch.
+	OverlapB int
+}
+
+// NewFailoverMultiplexReader creates a new source reader from equivalent
+// sources.  Reading starts with the first source.  On failure, or slowness
+// when hedging, the next source is opened at the offset of the first byte not
+// yet read.  The source that is open when the last sink is closed is closed
+// with it.
+func NewFailoverMultiplexReader(f Failover, opens ...OpenFunc) *MultiplexReader {
+	return NewFailoverMultiplexReaderWithSize(f, default_BLOCK_SIZE_B, opens...)
+}
+
+// NewFailoverMultiplexReaderWithSize creates a new failover source reader that
+// buffers in blocks of `size` bytes.  See NewFailoverMultiplexReader.
+func NewFailoverMultiplexReaderWithSize(f Failover, sizeB int, opens ...OpenFunc) *MultiplexReader {
+	if len(opens) == 0 {
+		panic("no sources")
+	}
+	mr := NewMultiplexReaderWithSize(&resumer{
+		opens:    opens,
+		policy:   f.Policy,
+		timeout:  f.Timeout,
+		overlapB: f.OverlapB,
+	}, sizeB)
+	mr.closeSrc = true
+	return mr
+}
+
+// NewResumableMultiplexReader creates a new source reader from open.  When
+// the source fails with an error other than io.EOF it is closed and, if
+// policy allows, reopened at the offset of the first byte not yet read so
+// that sinks never see the failure.  A nil policy never reopens.
+func NewResumableMultiplexReader(open OpenFunc, policy RetryPolicy) *MultiplexReader {
+	return NewResumableMultiplexReaderWithSize(open, policy, default_BLOCK_SIZE_B)
+}
+
+// NewResumableMultiplexReaderWithSize creates a new resumable source reader
+// that buffers in blocks of `size` bytes.  See NewResumableMultiplexReader.
+func NewResumableMultiplexReaderWithSize(open OpenFunc, policy RetryPolicy, sizeB int) *MultiplexReader {
+	return NewFailoverMultiplexReaderWithSize(Failover{Policy: policy}, sizeB, open)
+}
+
+// resumer reads a stream from its sources reopening them on failure.  offBi
+// tracks the offset of the next byte of the stream and tail holds the last
+// bytes read for comparison with reopened sources.  rc is only set with mtx
+// held so that Close can reach it while a read is in progress.
+type resumer struct {
+	opens    []OpenFunc
+	cur      int
+	policy   RetryPolicy
+	timeout  time.Duration
+	overlapB int
+	rc       io.ReadCloser
+	offBi    int64
+	tail     []byte
+	attempt  int
+	err      error
+	mtx      sync.Mutex
+	closed   bool
+}
+
+func (s *resumer) Read(bs []byte) (int, error) {
+	for s.err == nil {
+		if s.rc == nil {
+			if err := s.reopen(); err != nil {
+				s.retry(err)
+				continue
+			}
+		}
+		n, err := s.read(bs)
+		s.keep(bs[:n])
+		if n > 0 {
+			s.attempt = 0
+		}
+		if err == nil {
+			return n, nil
+		}
+		s.rc.Close()
+		s.setRC(nil)
+		if err == io.EOF {
+			s.err = err
+			return n, err
+		}
+		if n > 0 {
+			// deliver what was read.  the source is reopened on the next read
+			return n, nil
+		}
+		s.retry(err)
+	}
+	return 0, s.err
+}
+
+// Close closes the current source.  A read in progress fails and no further
+// source is opened.
+func (s *resumer) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.closed = true
+	if s.rc == nil {
+		return nil
+	}
+	return s.rc.Close()
+}
+
+// setRC makes rc the current source.  rc is closed at once if the resumer has
+// been closed.
+func (s *resumer) setRC(rc io.ReadCloser) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.closed && rc != nil {
+		rc.Close()
+	}
+	s.rc = rc
+}
+
+// isClosed reports whether Close has been called.
+func (s *resumer) isClosed() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.closed
+}
+
+// reopen opens the current source at the offset of the next byte, verifying
+// the overlap with the bytes already read.
+func (s *resumer) reopen() error {
+	if s.isClosed() {
+		return ErrClosedReader
+	}
+	k := int64(len(s.tail))
+	rc, err := s.opens[s.cur](s.offBi - k)
+	if err != nil {
+		return err
+	}
+	if k > 0 {
+		bs := make([]byte, k)
+		_, err = io.ReadFull(rc, bs)
+		if err == nil && !bytes.Equal(bs, s.tail) {
+			err = ErrSourceMismatch
+		}
+		if err != nil {
+			rc.Close()
+			return err
+		}
+	}
+	s.setRC(rc)
+	return nil
+}
+
+// read reads from the current source.  when hedging, a read that outlasts the
+// timeout is raced against a read of the next source.
+func (s *resumer) read(bs []byte) (int, error) {
+	if s.timeout <= 0 {
+		return s.rc.Read(bs)
+	}
+	c := goRead(s.rc, len(bs))
+	t := time.NewTimer(s.timeout)
+	defer t.Stop()
+	var res readResult
+	select {
+	case res = <-c:
+		return copy(bs, res.bs), res.err
+	case <-t.C:
+	}
+	prc, pcur := s.rc, s.cur
+	s.cur = (s.cur + 1) % len(s.opens)
+	s.setRC(nil)
+	if s.cur == pcur || s.reopen() != nil {
+		// no hedge available.  keep waiting
+		s.setRC(prc)
+		s.cur = pcur
+		res = <-c
+		return copy(bs, res.bs), res.err
+	}
+	hc := goRead(s.rc, len(bs))
+	select {
+	case res = <-c:
+		if res.err != nil && len(res.bs) == 0 {
+			// the slow source failed.  continue with the hedge
+			prc.Close()
+			res = <-hc
+			break
+		}
+		s.rc.Close()
+		s.setRC(prc)
+		s.cur = pcur
+	case res = <-hc:
+		if res.err != nil && len(res.bs) == 0 {
+			s.rc.Close()
+			s.setRC(prc)
+			s.cur = pcur
+			res = <-c
+			break
+		}
+		prc.Close()
+	}
+	return copy(bs, res.bs), res.err
+}
+
+// keep records bytes delivered from the stream.
+func (s *resumer) keep(bs []byte) {
+	s.offBi += int64(len(bs))
+	if s.overlapB <= 0 {
+		return
+	}
+	t := append(s.tail, bs...)
+	if len(t) > s.overlapB {
+		copy(t, t[len(t)-s.overlapB:])
+		t = t[:s.overlapB]
+	}
+	s.tail = t
+}
+
+// retry records a failure and moves on to the next source.  the failure is
+// final if the policy does not allow another attempt.
+func (s *resumer) retry(err error) {
+	s.attempt++
+	s.cur = (s.cur + 1) % len(s.opens)
+	if s.isClosed() || s.policy == nil || !s.policy(s.attempt, err) {
+		s.err = err
+	}
+}
+
+// readResult is the outcome of a read made in the background.
+type readResult struct {
+	bs  []byte
+	err error
+}
+
+// goRead reads up to n bytes from r in a new goroutine.  the result is
+// buffered so the goroutine completes even if it is abandoned.
+func goRead(r io.Reader, n int) <-chan readResult {
+	c := make(chan readResult, 1)
+	go func() {
+		bs := make([]byte, n)
+		m, err := r.Read(bs)
+		c <- readResult{bs[:m], err}
+	}()
+	return c
+}
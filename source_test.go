@@ -0,0 +1,207 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flaky reads a string failing after every `fail` bytes
+type flaky struct {
+	s     string
+	fail  int
+	opens int
+}
+
+func (f *flaky) open(offset int64) (io.ReadCloser, error) {
+	f.opens++
+	if offset > int64(len(f.s)) {
+		return nil, errors.New("bad offset")
+	}
+	if len(f.s)-int(offset) <= f.fail {
+		return ioutil.NopCloser(strings.NewReader(f.s[offset:])), nil
+	}
+	return ioutil.NopCloser(io.MultiReader(
+		io.LimitReader(strings.NewReader(f.s[offset:]), int64(f.fail)),
+		&errReader{errors.New("flaky")},
+	)), nil
+}
+
+func TestResumable(t *testing.T) {
+
+	f := &flaky{s: LONG_GREEK, fail: 100}
+	mr := NewResumableMultiplexReaderWithSize(f.open, Retry(1, 0), 64)
+
+	r0 := mr.NewReader()
+	r1 := mr.NewReader()
+
+	buf := &bytes.Buffer{}
+	_, err := io.Copy(buf, r0)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if buf.String() != LONG_GREEK {
+		t.Fatalf("unexpected value")
+	}
+	if f.opens != (len(LONG_GREEK)+99)/100 {
+		t.Fatalf("unexpected value: %d", f.opens)
+	}
+	bs, err := ioutil.ReadAll(r1)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(bs) != LONG_GREEK {
+		t.Fatalf("unexpected value")
+	}
+
+}
+
+func TestResumableExhausted(t *testing.T) {
+
+	f := &flaky{s: LONG_GREEK, fail: 0}
+	mr := NewResumableMultiplexReader(f.open, Retry(3, 0))
+
+	r0 := mr.NewReader()
+	_, err := ioutil.ReadAll(r0)
+	if err == nil || err.Error() != "flaky" {
+		t.Fatalf("unexpected value: %v", err)
+	}
+	if f.opens != 4 {
+		t.Fatalf("unexpected value: %d", f.opens)
+	}
+
+}
+
+// closeCounter counts the times it is closed
+type closeCounter struct {
+	io.Reader
+	closes int
+}
+
+func (c *closeCounter) Close() error {
+	c.closes++
+	return nil
+}
+
+func TestResumableClose(t *testing.T) {
+
+	c := &closeCounter{Reader: strings.NewReader(LONG_GREEK)}
+	mr := NewResumableMultiplexReaderWithSize(func(offset int64) (io.ReadCloser, error) {
+		return c, nil
+	}, nil, 10)
+
+	r0 := mr.NewReader()
+	r1 := mr.NewReader()
+
+	bs := make([]byte, 5)
+	if _, err := r0.Read(bs); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	r0.Close()
+	if c.closes != 0 {
+		t.Fatalf("unexpected value")
+	}
+	// the source is closed with the last sink, before its end
+	r1.Close()
+	if c.closes != 1 {
+		t.Fatalf("unexpected value: %d", c.closes)
+	}
+
+}
+
+// slow delays every read
+type slow struct {
+	io.Reader
+	d time.Duration
+}
+
+func (s *slow) Read(bs []byte) (int, error) {
+	time.Sleep(s.d)
+	return s.Reader.Read(bs)
+}
+
+func TestFailover(t *testing.T) {
+
+	primary := &flaky{s: LONG_GREEK, fail: 1000}
+	other := strings.ToUpper(LONG_GREEK)
+	opens := []OpenFunc{
+		primary.open,
+		func(offset int64) (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader(LONG_GREEK[offset:])), nil
+		},
+		func(offset int64) (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader(other[offset:])), nil
+		},
+	}
+
+	mr := NewFailoverMultiplexReaderWithSize(Failover{Policy: Retry(1, 0), OverlapB: 16}, 64, opens...)
+	r0 := mr.NewReader()
+	bs, err := ioutil.ReadAll(r0)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(bs) != LONG_GREEK {
+		t.Fatalf("unexpected value")
+	}
+
+	// a replica that differs is detected on switch
+	mr = NewFailoverMultiplexReaderWithSize(Failover{Policy: Retry(1, 0), OverlapB: 16}, 64, opens[0], opens[2])
+	r0 = mr.NewReader()
+	bs, err = ioutil.ReadAll(r0)
+	if err != ErrSourceMismatch {
+		t.Fatalf("unexpected value: %v", err)
+	}
+	if string(bs) != LONG_GREEK[:1000] {
+		t.Fatalf("unexpected value")
+	}
+
+}
+
+func TestFailoverHedged(t *testing.T) {
+
+	opens := []OpenFunc{
+		func(offset int64) (io.ReadCloser, error) {
+			return ioutil.NopCloser(&slow{strings.NewReader(LONG_GREEK[offset:]), time.Hour}), nil
+		},
+		func(offset int64) (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader(LONG_GREEK[offset:])), nil
+		},
+	}
+
+	mr := NewFailoverMultiplexReader(Failover{Timeout: 10 * time.Millisecond}, opens...)
+	r0 := mr.NewReader()
+	bs, err := ioutil.ReadAll(r0)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(bs) != LONG_GREEK {
+		t.Fatalf("unexpected value")
+	}
+
+}
@@ -0,0 +1,110 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// SetSparse distributes blocks of zeros as holes.  Sinks writing to a
+// regular *os.File, not opened for appending, with WriteTo skip over holes rather than writing them, punching
+// them out of data already in the file where the platform allows, and other
+// sinks receive the zeros.  Blocks of zeros share one buffer while they are
+// queued.  On linux, the holes of an *os.File source are found with
+// SEEK_DATA and SEEK_HOLE and are not read.
+//
+// SetSparse must be called before the first read.
+func (mr *MultiplexReader) SetSparse() {
+	mr.mtx.Lock()
+	defer mr.mtx.Unlock()
+	if mr.baseBi > 0 {
+		panic("late start")
+	}
+	mr.sparse = true
+	if f, ok := mr.rdr.(*os.File); ok {
+		if s := sparseSource(f); s != nil {
+			mr.rdr = s
+		}
+	}
+}
+
+// hole reports whether bs is all zeros in a sparse source, and if so returns
+// the shared block of zeros in its place.  called with the lock held.
+func (mr *MultiplexReader) hole(bs []byte) ([]byte, bool) {
+	if !mr.sparse || len(bs) == 0 {
+		return bs, false
+	}
+	if len(mr.zeros) < len(bs) {
+		mr.zeros = make([]byte, len(bs))
+	}
+	// limit the capacity so that the zeros are never appended to
+	z := mr.zeros[:len(bs):len(bs)]
+	if !bytes.Equal(bs, z) {
+		return bs, false
+	}
+	return z, true
+}
+
+// seekable reports whether holes can be skipped in f.  Pipes, sockets and
+// terminals cannot seek, and writes to a file opened for appending land at
+// its end wherever it has seeked to.
+func seekable(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode().IsRegular() && !appending(f)
+}
+
+// writeHole skips over the zeros zs in f, clearing any data that is there.
+func writeHole(f *os.File, zs []byte) error {
+	off, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if off < fi.Size() && punchHole(f, off, int64(len(zs))) != nil {
+		_, err := f.Write(zs)
+		return err
+	}
+	_, err = f.Seek(int64(len(zs)), io.SeekCurrent)
+	return err
+}
+
+// endHoles extends f to its offset when it ends in a hole.
+func endHoles(f *os.File) error {
+	off, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Size() < off {
+		return f.Truncate(off)
+	}
+	return nil
+}
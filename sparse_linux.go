@@ -0,0 +1,138 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+//go:build linux
+// +build linux
+
+package multio
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+const (
+	_SEEK_DATA            = 3
+	_SEEK_HOLE            = 4
+	_FALLOC_FL_KEEP_SIZE  = 1
+	_FALLOC_FL_PUNCH_HOLE = 2
+)
+
+// punchHole deallocates n bytes of f at off.
+func punchHole(f *os.File, off, n int64) error {
+	return syscall.Fallocate(int(f.Fd()), _FALLOC_FL_PUNCH_HOLE|_FALLOC_FL_KEEP_SIZE, off, n)
+}
+
+// appending reports whether f was opened with O_APPEND.
+func appending(f *os.File) bool {
+	fl, _, errno := syscall.Syscall(syscall.SYS_FCNTL, f.Fd(), _F_GETFL, 0)
+	return errno != 0 || fl&syscall.O_APPEND != 0
+}
+
+// sparseFile reads a regular file without reading its holes.
+type sparseFile struct {
+	f     *os.File
+	zeros []byte
+	// plain is set once the file system does not find holes
+	plain bool
+}
+
+func sparseSource(f *os.File) io.Reader {
+	fi, err := f.Stat()
+	if err != nil || !fi.Mode().IsRegular() {
+		return nil
+	}
+	return &sparseFile{f: f}
+}
+
+// Read fulfills the io.Reader interface for use outside of a MultiplexReader.
+func (s *sparseFile) Read(bs []byte) (int, error) {
+	brs, err := s.readBlock(len(bs))
+	return copy(bs, brs), err
+}
+
+func (s *sparseFile) readBlock(sizeB int) ([]byte, error) {
+	if s.plain {
+		return s.read(sizeB)
+	}
+	cur, err := s.f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.f.Seek(cur, _SEEK_DATA)
+	if isErrno(err, syscall.ENXIO) {
+		// a hole runs to the end of the file
+		fi, serr := s.f.Stat()
+		if serr != nil {
+			return nil, serr
+		}
+		if cur >= fi.Size() {
+			return nil, io.EOF
+		}
+		data, err = fi.Size(), nil
+	}
+	if err != nil {
+		s.plain = true
+		if _, err := s.f.Seek(cur, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return s.read(sizeB)
+	}
+	if data > cur {
+		n := sizeB
+		if data-cur < int64(n) {
+			n = int(data - cur)
+		}
+		if _, err := s.f.Seek(cur+int64(n), io.SeekStart); err != nil {
+			return nil, err
+		}
+		if len(s.zeros) < n {
+			s.zeros = make([]byte, n)
+		}
+		return s.zeros[:n:n], nil
+	}
+	// read the data up to the next hole
+	if end, err := s.f.Seek(cur, _SEEK_HOLE); err == nil && end > cur && end-cur < int64(sizeB) {
+		sizeB = int(end - cur)
+	}
+	if _, err := s.f.Seek(cur, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return s.read(sizeB)
+}
+
+func (s *sparseFile) read(sizeB int) ([]byte, error) {
+	bs := make([]byte, sizeB)
+	n, err := io.ReadFull(s.f, bs)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return bs[:n], err
+}
+
+func isErrno(err error, errno syscall.Errno) bool {
+	if perr, ok := err.(*os.PathError); ok {
+		err = perr.Err
+	}
+	return err == errno
+}
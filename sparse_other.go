@@ -0,0 +1,49 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+//go:build !linux
+// +build !linux
+
+package multio
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// punchHole is only available on linux.
+func punchHole(f *os.File, off, n int64) error {
+	return errors.New("punch hole unsupported")
+}
+
+// sparseSource is only available on linux.
+func sparseSource(f *os.File) io.Reader {
+	return nil
+}
+
+// appending reports whether f was opened with O_APPEND by OpenFile, which
+// refuses WriteAt for such files.
+func appending(f *os.File) bool {
+	_, err := f.WriteAt(nil, 0)
+	return err != nil
+}
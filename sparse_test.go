@@ -0,0 +1,216 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// sparseImage returns an image of data and zeros that ends in zeros
+func sparseImage() []byte {
+	img := make([]byte, 1<<22)
+	rnd := rand.New(rand.NewSource(1))
+	rnd.Read(img[:1<<16])
+	rnd.Read(img[1<<20 : 1<<20+1000])
+	rnd.Read(img[3<<20 : 3<<20+1<<16])
+	return img
+}
+
+func replicateSparse(t *testing.T, mr *MultiplexReader, f *os.File) []byte {
+	r0 := mr.NewReader()
+	r1 := mr.NewReader()
+	buf := &bytes.Buffer{}
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if _, err := r0.WriteTo(f); err != nil {
+			t.Errorf("err: %v", err)
+		}
+		r0.Close()
+	}()
+	go func() {
+		defer wg.Done()
+		if _, err := r1.WriteTo(buf); err != nil {
+			t.Errorf("err: %v", err)
+		}
+		r1.Close()
+	}()
+	wg.Wait()
+	return buf.Bytes()
+}
+
+func TestSparse(t *testing.T) {
+
+	img := sparseImage()
+	mr := NewMultiplexReaderWithSize(bytes.NewReader(img), 1<<16)
+	mr.SetSparse()
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "dst"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+
+	// the file skips the holes and the buffer receives the zeros
+	bs := replicateSparse(t, mr, f)
+	if !bytes.Equal(bs, img) {
+		t.Fatalf("unexpected value")
+	}
+	got, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !bytes.Equal(got, img) {
+		t.Fatalf("unexpected value")
+	}
+
+}
+
+func TestSparseOverwrite(t *testing.T) {
+
+	img := sparseImage()
+	name := filepath.Join(t.TempDir(), "dst")
+	old := make([]byte, len(img)+100)
+	rand.New(rand.NewSource(2)).Read(old)
+	if err := ioutil.WriteFile(name, old, 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+
+	mr := NewMultiplexReaderWithSize(bytes.NewReader(img), 1<<16)
+	mr.SetSparse()
+	replicateSparse(t, mr, f)
+
+	// holes clear the data they land on
+	got, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !bytes.Equal(got[:len(img)], img) || !bytes.Equal(got[len(img):], old[len(img):]) {
+		t.Fatalf("unexpected value")
+	}
+
+}
+
+func TestSparseUnseekable(t *testing.T) {
+
+	img := sparseImage()
+
+	// a pipe receives the zeros
+	mr := NewMultiplexReaderWithSize(bytes.NewReader(img), 1<<16)
+	mr.SetSparse()
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer pr.Close()
+	c := make(chan []byte)
+	go func() {
+		bs, _ := ioutil.ReadAll(pr)
+		c <- bs
+	}()
+	bs := replicateSparse(t, mr, pw)
+	pw.Close()
+	if !bytes.Equal(bs, img) || !bytes.Equal(<-c, img) {
+		t.Fatalf("unexpected value")
+	}
+
+	// as does a file opened for appending, after what it holds
+	name := filepath.Join(t.TempDir(), "dst")
+	if err := ioutil.WriteFile(name, []byte("head"), 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+	mr = NewMultiplexReaderWithSize(bytes.NewReader(img), 1<<16)
+	mr.SetSparse()
+	replicateSparse(t, mr, f)
+	got, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(got[:4]) != "head" || !bytes.Equal(got[4:], img) {
+		t.Fatalf("unexpected value")
+	}
+
+}
+
+func TestSparseSource(t *testing.T) {
+
+	img := sparseImage()
+	dir := t.TempDir()
+	src, err := os.Create(filepath.Join(dir, "src"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer src.Close()
+	// write only the data so the zeros are holes
+	for i := 0; i < len(img); i += 1 << 12 {
+		blk := img[i : i+1<<12]
+		if !bytes.Equal(blk, make([]byte, len(blk))) {
+			src.WriteAt(blk, int64(i))
+		}
+	}
+	src.Truncate(int64(len(img)))
+	src.Seek(0, io.SeekStart)
+
+	mr := NewMultiplexReaderWithSize(src, 1<<16)
+	mr.SetSparse()
+	if _, ok := mr.rdr.(*os.File); ok && runtime.GOOS == "linux" {
+		t.Fatalf("unexpected value")
+	}
+
+	f, err := os.Create(filepath.Join(dir, "dst"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+	bs := replicateSparse(t, mr, f)
+	if !bytes.Equal(bs, img) {
+		t.Fatalf("unexpected value")
+	}
+	got, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !bytes.Equal(got, img) {
+		t.Fatalf("unexpected value")
+	}
+
+}
@@ -0,0 +1,162 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import (
+	"io"
+	"sync"
+)
+
+// Transform converts the stream read from a parent sink into the stream
+// replicated by a child MultiplexReader.
+type Transform func(r io.Reader) io.Reader
+
+// NewMultiplexReader creates a child MultiplexReader whose source is this sink
+// passed through t.  A nil t replicates the sink unchanged.
+//
+// The child reads from the sink only when one of its own sinks reads, so
+// backpressure carries from the leaves of the tree up to the root source.
+// Errors from the parent stream are delivered to every sink of the child.
+// When the last sink of the child is closed the sink is closed with the same
+// error, along with the transformed source if it is an io.Closer.  A sink
+// read by a WriterTransform is closed once the transformation stops.  The
+// sink must not be read or closed directly once it has a child.
+func (r *Reader) NewMultiplexReader(t Transform) *MultiplexReader {
+	return r.NewMultiplexReaderWithSize(t, default_BLOCK_SIZE_B)
+}
+
+// NewMultiplexReaderWithSize creates a child MultiplexReader that buffers in
+// blocks of `size` bytes.  See NewMultiplexReader.
+func (r *Reader) NewMultiplexReaderWithSize(t Transform, sizeB int) *MultiplexReader {
+	var src io.Reader = r
+	if t != nil {
+		src = t(r)
+	}
+	q := NewMultiplexReaderWithSize(src, sizeB)
+	q.parent = r
+	q.closeSrc = t != nil
+	return q
+}
+
+// detach releases the source of a multiplexer once its last sink is removed:
+// the source itself if it was created by this package and the parent sink of
+// a child multiplexer.  called with the lock held.
+func (mr *MultiplexReader) detach(err error) {
+	if mr.closeSrc {
+		mr.closeSrc = false
+		if c, ok := mr.rdr.(io.Closer); ok {
+			c.Close()
+		}
+	}
+	p := mr.parent
+	if p == nil {
+		return
+	}
+	mr.parent = nil
+	if t, ok := mr.rdr.(*writerTransform); ok {
+		// the transform may be reading the sink in its own goroutine
+		t.closeParent(p, err)
+		return
+	}
+	p.CloseWithError(err)
+}
+
+// WriterTransform adapts a writer side transformation, such as
+// gzip.NewWriter, to a Transform.  The returned io.WriteCloser is closed
+// at the end of the stream to flush it.
+func WriterTransform(fn func(w io.Writer) io.WriteCloser) Transform {
+	return func(r io.Reader) io.Reader {
+		pr, pw := io.Pipe()
+		return &writerTransform{
+			src: r,
+			fn:  fn,
+			pr:  pr,
+			pw:  pw,
+		}
+	}
+}
+
+// writerTransform runs a writer side transformation in its own goroutine,
+// which reads the parent sink.  once detached, the parent is closed by
+// that goroutine when it exits, or at once if it is not running.
+type writerTransform struct {
+	once     sync.Once
+	src      io.Reader
+	fn       func(w io.Writer) io.WriteCloser
+	pr       *io.PipeReader
+	pw       *io.PipeWriter
+	mtx      sync.Mutex
+	running  bool
+	parent   *Reader
+	detached bool
+	err      error
+}
+
+// Read starts the transformation on first use so that the parent is not read
+// before all of its sinks have been created.
+func (t *writerTransform) Read(bs []byte) (int, error) {
+	t.once.Do(func() {
+		t.mtx.Lock()
+		defer t.mtx.Unlock()
+		if !t.detached {
+			t.running = true
+			go t.run()
+		}
+	})
+	return t.pr.Read(bs)
+}
+
+func (t *writerTransform) run() {
+	w := t.fn(t.pw)
+	_, err := io.Copy(w, t.src)
+	cerr := w.Close()
+	if err == nil {
+		err = cerr
+	}
+	t.pw.CloseWithError(err)
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.running = false
+	if t.detached {
+		t.parent.CloseWithError(t.err)
+	}
+}
+
+// Close stops the transformation.  pending writes to the pipe fail and
+// release the goroutine running it.
+func (t *writerTransform) Close() error {
+	return t.pr.Close()
+}
+
+// closeParent closes the parent sink p with err once the transformation has
+// stopped reading it.
+func (t *writerTransform) closeParent(p *Reader, err error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.detached = true
+	t.parent = p
+	t.err = err
+	if !t.running {
+		p.CloseWithError(err)
+	}
+}
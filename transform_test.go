@@ -0,0 +1,219 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTreeGzip(t *testing.T) {
+
+	mr := NewMultiplexReaderWithSize(strings.NewReader(LONG_GREEK), 64)
+
+	r0 := mr.NewReaderWithLength(4)
+	r1 := mr.NewReaderWithLength(4)
+
+	// five sinks share one compressor
+	cmr := r1.NewMultiplexReaderWithSize(WriterTransform(func(w io.Writer) io.WriteCloser {
+		return gzip.NewWriter(w)
+	}), 64)
+
+	N := 5
+	rss := make([]*Reader, N)
+	bufs := make([]*bytes.Buffer, N)
+	for i := 0; i < N; i++ {
+		rss[i] = cmr.NewReaderWithLength(4)
+		bufs[i] = &bytes.Buffer{}
+	}
+
+	wg := sync.WaitGroup{}
+	buf0 := &bytes.Buffer{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		io.Copy(buf0, r0)
+		r0.Close()
+	}()
+	for i := 0; i < N; i++ {
+		wg.Add(1)
+		go func(j int) {
+			defer wg.Done()
+			io.Copy(bufs[j], rss[j])
+			rss[j].Close()
+		}(i)
+	}
+	wg.Wait()
+
+	if buf0.String() != LONG_GREEK {
+		t.Fatalf("unexpected value")
+	}
+	for i := 1; i < N; i++ {
+		if !bytes.Equal(bufs[0].Bytes(), bufs[i].Bytes()) {
+			t.Fatalf("unexpected value")
+		}
+	}
+	zr, err := gzip.NewReader(bufs[0])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	bs, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(bs) != LONG_GREEK {
+		t.Fatalf("unexpected value")
+	}
+
+}
+
+func TestTreeClose(t *testing.T) {
+
+	mr := NewMultiplexReaderWithSize(strings.NewReader(LONG_GREEK), 10)
+
+	r0 := mr.NewReaderWithLength(2)
+	r1 := mr.NewReaderWithLength(2)
+
+	cmr := r1.NewMultiplexReaderWithSize(nil, 5)
+	c0 := cmr.NewReader()
+	c1 := cmr.NewReader()
+
+	bs := make([]byte, 5)
+	n, err := c0.Read(bs)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(bs[:n]) != "Lorem" {
+		t.Fatalf("unexpected value")
+	}
+
+	c0.Close()
+	if r1.closed {
+		t.Fatalf("unexpected value")
+	}
+	c1.CloseWithError(errors.New("testing"))
+	if !r1.closed {
+		t.Fatalf("unexpected value")
+	}
+	if r1.err == nil || r1.err.Error() != "testing" {
+		t.Fatalf("unexpected value")
+	}
+
+	// the remaining sink is not held back by the closed branch
+	buf := &bytes.Buffer{}
+	_, err = io.Copy(buf, r0)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if buf.String() != LONG_GREEK {
+		t.Fatalf("unexpected value")
+	}
+
+}
+
+func TestTreeGzipCloseEarly(t *testing.T) {
+
+	mr := NewMultiplexReaderWithSize(strings.NewReader(strings.Repeat(LONG_GREEK, 20)), 64)
+
+	r0 := mr.NewReaderWithLength(4)
+	r1 := mr.NewReaderWithLength(4)
+	cmr := r1.NewMultiplexReaderWithSize(WriterTransform(func(w io.Writer) io.WriteCloser {
+		return gzip.NewWriter(w)
+	}), 64)
+	c0 := cmr.NewReaderWithLength(4)
+	c1 := cmr.NewReaderWithLength(4)
+
+	wg := sync.WaitGroup{}
+	for _, c := range []*Reader{c0, c1} {
+		wg.Add(1)
+		go func(c *Reader) {
+			defer wg.Done()
+			bs := make([]byte, 10)
+			for i := 0; i < 3; i++ {
+				if _, err := c.Read(bs); err != nil {
+					t.Errorf("err: %v", err)
+				}
+			}
+			c.Close()
+		}(c)
+	}
+
+	// the sibling of the branch reads on while the branch closes
+	buf := &bytes.Buffer{}
+	_, err := io.Copy(buf, r0)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	wg.Wait()
+	if buf.String() != strings.Repeat(LONG_GREEK, 20) {
+		t.Fatalf("unexpected value")
+	}
+	r0.Close()
+
+	// the branch is closed once the transformation stops
+	for i := 0; i < 100; i++ {
+		r1.mr.mtx.Lock()
+		closed := r1.closed
+		r1.mr.mtx.Unlock()
+		if closed {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("unexpected value")
+
+}
+
+func TestTreeError(t *testing.T) {
+
+	mr := NewMultiplexReaderWithSize(io.MultiReader(strings.NewReader(SHORT_GREEK), &errReader{errors.New("testing")}), 5)
+
+	r0 := mr.NewReader()
+	cmr := r0.NewMultiplexReaderWithSize(nil, 5)
+	c0 := cmr.NewReader()
+
+	bs, err := ioutil.ReadAll(c0)
+	if err == nil || err.Error() != "testing" {
+		t.Fatalf("unexpected value: %v", err)
+	}
+	if string(bs) != SHORT_GREEK {
+		t.Fatalf("unexpected value")
+	}
+	c0.Close()
+
+}
+
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read(bs []byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,89 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+)
+
+// VerifyError reports a replica whose content does not match its source.
+type VerifyError struct {
+	Name string
+	// Blocks lists the indexes of the blocks of the MerkleTree of the source
+	// that do not match, including len(Blocks) if the replica is longer.
+	Blocks []int
+	// Offset is the offset of the first block that does not match.
+	Offset int64
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("%s: %d blocks corrupt, first at offset %d", e.Name, len(e.Blocks), e.Offset)
+}
+
+// VerifyFiles reads back each of the files names and compares it with the
+// source of the tree t, taken from MerkleTree.  Files that do not match are
+// reported with a *VerifyError, and files that cannot be read with their
+// error, in a *CopyError.  Where the platform allows, the files are read from
+// the device rather than from cached pages of files that have been synced.
+func VerifyFiles(t *MerkleTree, names ...string) error {
+	errs := make([]error, len(names))
+	failed := false
+	for i, name := range names {
+		errs[i] = verifyFile(t, name, name)
+		failed = failed || errs[i] != nil
+	}
+	if failed {
+		return &CopyError{Errs: errs}
+	}
+	return nil
+}
+
+// verifyFile compares the file at path with the tree t, reporting it as name.
+func verifyFile(t *MerkleTree, path, name string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dropCache(f)
+	is, err := t.Verify(bufio.NewReaderSize(f, default_BLOCK_SIZE_B))
+	if err != nil {
+		return err
+	}
+	if len(is) == 0 {
+		return nil
+	}
+	verr := &VerifyError{
+		Name:   name,
+		Blocks: is,
+	}
+	if is[0] < len(t.Blocks) {
+		verr.Offset = t.Blocks[is[0]].Offset
+	} else if len(t.Blocks) > 0 {
+		last := t.Blocks[len(t.Blocks)-1]
+		verr.Offset = last.Offset + int64(last.Length)
+	}
+	return verr
+}
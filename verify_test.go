@@ -0,0 +1,139 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyFiles(t *testing.T) {
+
+	dir := t.TempDir()
+	names := []string{
+		filepath.Join(dir, "a"),
+		filepath.Join(dir, "b"),
+		filepath.Join(dir, "c"),
+		filepath.Join(dir, "missing"),
+	}
+
+	mr := NewMultiplexReaderWithSize(strings.NewReader(LONG_GREEK), 100)
+	mr.SetBlockChecksums(DigestSHA256)
+	ws := []io.Writer{}
+	for _, name := range names[:3] {
+		f, err := os.Create(name)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		defer f.Close()
+		ws = append(ws, f)
+	}
+	if _, err := mr.copyTo(ws); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	mt, err := mr.MerkleTree()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := VerifyFiles(mt, names[:3]...); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// corrupt one block of b and lengthen c
+	bad := []byte(LONG_GREEK)
+	bad[250] ^= 1
+	ioutil.WriteFile(names[1], bad, 0600)
+	ioutil.WriteFile(names[2], []byte(LONG_GREEK+"x"), 0600)
+
+	err = VerifyFiles(mt, names...)
+	cerr, ok := err.(*CopyError)
+	if !ok {
+		t.Fatalf("unexpected value: %v", err)
+	}
+	if cerr.Errs[0] != nil || !os.IsNotExist(cerr.Errs[3]) {
+		t.Fatalf("unexpected value: %v", cerr.Errs)
+	}
+	verr, ok := cerr.Errs[1].(*VerifyError)
+	if !ok || verr.Name != names[1] || len(verr.Blocks) != 1 || verr.Blocks[0] != 2 || verr.Offset != 200 {
+		t.Fatalf("unexpected value: %v", cerr.Errs[1])
+	}
+	verr, ok = cerr.Errs[2].(*VerifyError)
+	if !ok || verr.Blocks[0] != len(mt.Blocks) || verr.Offset != int64(len(LONG_GREEK)) {
+		t.Fatalf("unexpected value: %v", cerr.Errs[2])
+	}
+
+}
+
+func TestCopyFileVerify(t *testing.T) {
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := ioutil.WriteFile(src, []byte(LONG_GREEK), 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	dst := filepath.Join(dir, "dst")
+	if _, err := CopyFileWithOptions(src, CopyOptions{Sync: true, Verify: true}, dst); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if bs, _ := ioutil.ReadFile(dst); string(bs) != LONG_GREEK {
+		t.Fatalf("unexpected value")
+	}
+
+	// a replica that does not read back as written is not committed
+	a, err := CreateAtomic(filepath.Join(dir, "bad"), 0600)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	mr := NewMultiplexReaderWithSize(strings.NewReader(LONG_GREEK), 100)
+	mr.SetBlockChecksums(DigestSHA256)
+	if _, err := mr.copyTo([]io.Writer{ioutil.Discard}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	io.WriteString(a, LONG_GREEK[:150])
+	fi, _ := os.Stat(src)
+	m := &metaFile{
+		AtomicFile: a,
+		fi:         fi,
+		verify:     true,
+		mr:         mr,
+	}
+	_, err = replicate([]Committer{m}, make([]error, 1), 0, func(dsts []io.Writer) (int64, error) {
+		return 0, nil
+	})
+	cerr, ok := err.(*CopyError)
+	if !ok {
+		t.Fatalf("unexpected value: %v", err)
+	}
+	verr, ok := cerr.Errs[0].(*VerifyError)
+	if !ok || verr.Name != a.Name() || verr.Offset != 100 {
+		t.Fatalf("unexpected value: %v", cerr.Errs[0])
+	}
+	if fis, _ := ioutil.ReadDir(dir); len(fis) != 2 {
+		t.Fatalf("unexpected value")
+	}
+
+}
@@ -0,0 +1,141 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package multio
+
+import (
+	"io"
+	"net"
+	"os"
+)
+
+const (
+	default_WRITE_BATCH_B = 1 << 20
+	// max_IOV bounds the buffers gathered into one write
+	max_IOV = 1 << 10
+)
+
+// SetWriteBatch sets the most bytes that WriteTo gathers from blocks already
+// queued for the sink into one vectored write.  Blocks are written with
+// writev(2) to files on linux and to network connections that support it, and
+// one at a time to other writers.  A maxB of zero or less writes each block
+// with its own call.  The default is 1 MiB.  Sinks of a source in packet mode
+// always write each message with its own call.
+//
+// SetWriteBatch must not be called during WriteTo.
+func (r *Reader) SetWriteBatch(maxB int) {
+	r.batchB = maxB
+}
+
+// writeBatch writes the current block along with the blocks already queued
+// after it.  It returns the bytes written from the current block, the bytes
+// written from the blocks after it and the last entry written whole.  Entries
+// not written whole are returned to the front of the queue.  The batch ends
+// before a hole if holes is set.
+func (r *Reader) writeBatch(w io.Writer, holes bool) (n int, extra int64, last *entry, err error) {
+	bufs := [][]byte{r.buf}
+	ents := []entry{}
+	total := len(r.buf)
+	for total < r.batchB && len(bufs) < max_IOV {
+		ent, ok := r.poll()
+		if !ok {
+			break
+		}
+		if holes && ent.hole {
+			r.q = append([]entry{ent}, r.q...)
+			break
+		}
+		ents = append(ents, ent)
+		bufs = append(bufs, ent.bs)
+		total += len(ent.bs)
+		if ent.err != nil {
+			break
+		}
+	}
+	m, err := writeBuffers(w, bufs)
+	n = len(r.buf)
+	if m < int64(n) {
+		n = int(m)
+	}
+	extra = m - int64(n)
+	m = extra
+	k := 0
+	for ; k < len(ents) && m >= int64(len(ents[k].bs)); k++ {
+		m -= int64(len(ents[k].bs))
+	}
+	if k > 0 {
+		last = &ents[k-1]
+	}
+	if k < len(ents) {
+		rest := append([]entry(nil), ents[k:]...)
+		if m > 0 {
+			rest[0].i += m
+			rest[0].bs = rest[0].bs[m:]
+			rest[0].sum = nil
+		}
+		r.q = append(rest, r.q...)
+		if err == nil {
+			err = io.ErrShortWrite
+		}
+	}
+	if err == nil && n < len(r.buf) {
+		err = io.ErrShortWrite
+	}
+	return n, extra, last, err
+}
+
+// poll returns the next entry queued for the sink without waiting.
+func (r *Reader) poll() (entry, bool) {
+	if len(r.q) > 0 {
+		return r.pop(), true
+	}
+	select {
+	case ent, ok := <-r.c:
+		return ent, ok
+	default:
+		return entry{}, false
+	}
+}
+
+// skip brings the sink up to date with the last entry written whole by
+// writeBatch.
+func (r *Reader) skip(last *entry) {
+	r.baseBi = last.i + int64(len(last.bs))
+	r.seq = last.seq
+	r.err = last.err
+	r.sum = nil
+}
+
+// writeBuffers writes bufs to w in as few calls as w allows.
+func writeBuffers(w io.Writer, bufs [][]byte) (int64, error) {
+	if len(bufs) == 1 {
+		n, err := w.Write(bufs[0])
+		return int64(n), err
+	}
+	if f, ok := w.(*os.File); ok {
+		if handled, n, err := writevFile(f, bufs); handled {
+			return n, err
+		}
+	}
+	nb := net.Buffers(bufs)
+	return nb.WriteTo(w)
+}
@@ -0,0 +1,80 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+//go:build linux
+// +build linux
+
+package multio
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// writevFile writes bufs to f with writev(2).
+func writevFile(f *os.File, bufs [][]byte) (handled bool, nn int64, err error) {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return false, 0, nil
+	}
+	iovs := make([]syscall.Iovec, 0, len(bufs))
+	for {
+		iovs = iovs[:0]
+		for _, bs := range bufs {
+			if len(bs) > 0 {
+				iov := syscall.Iovec{Base: &bs[0]}
+				iov.SetLen(len(bs))
+				iovs = append(iovs, iov)
+			}
+		}
+		if len(iovs) == 0 {
+			return true, nn, nil
+		}
+		var n uintptr
+		var errno syscall.Errno
+		werr := rc.Write(func(fd uintptr) bool {
+			n, _, errno = syscall.Syscall(syscall.SYS_WRITEV, fd, uintptr(unsafe.Pointer(&iovs[0])), uintptr(len(iovs)))
+			return errno != syscall.EAGAIN
+		})
+		if werr != nil {
+			return true, nn, werr
+		}
+		if errno == syscall.EINTR {
+			continue
+		}
+		if errno != 0 {
+			return true, nn, &os.PathError{Op: "writev", Path: f.Name(), Err: errno}
+		}
+		nn += int64(n)
+		// drop what was written and go around for the rest
+		for n > 0 && len(bufs) > 0 {
+			if int(n) < len(bufs[0]) {
+				bufs[0] = bufs[0][n:]
+				n = 0
+				break
+			}
+			n -= uintptr(len(bufs[0]))
+			bufs = bufs[1:]
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// MIT License
+//
+// Copyright (c) 2019 Aaron H. Alpar
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+package multio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writes records each write
+type writes struct {
+	ws []string
+}
+
+func (w *writes) Write(bs []byte) (int, error) {
+	w.ws = append(w.ws, string(bs))
+	return len(bs), nil
+}
+
+func TestWriteBatchFile(t *testing.T) {
+
+	mr := NewMultiplexReaderWithSize(strings.NewReader(LONG_GREEK), 10)
+	r0 := mr.NewReader()
+	r1 := mr.NewReader()
+	r1.SetWriteBatch(100)
+
+	// queue the whole source for r1
+	if _, err := ioutil.ReadAll(r0); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	r0.Close()
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "dst"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+	n, err := r1.WriteTo(f)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if n != int64(len(LONG_GREEK)) {
+		t.Fatalf("unexpected value: %d", n)
+	}
+	bs, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(bs) != LONG_GREEK {
+		t.Fatalf("unexpected value")
+	}
+	if _, err := r1.Read(bs); err != io.EOF {
+		t.Fatalf("unexpected value: %v", err)
+	}
+
+}
+
+func TestWriteBatchConn(t *testing.T) {
+
+	mr := NewMultiplexReaderWithSize(strings.NewReader(LONG_GREEK), 10)
+	r0 := mr.NewReader()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer ln.Close()
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	peer, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer peer.Close()
+
+	c := make(chan []byte)
+	go func() {
+		bs, _ := ioutil.ReadAll(peer)
+		c <- bs
+	}()
+	if _, err := r0.WriteTo(conn); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	conn.Close()
+	if string(<-c) != LONG_GREEK {
+		t.Fatalf("unexpected value")
+	}
+
+}
+
+func TestWriteBatchShort(t *testing.T) {
+
+	mr := NewMultiplexReaderWithSize(strings.NewReader(LONG_GREEK), 10)
+	r0 := mr.NewReader()
+	r1 := mr.NewReader()
+	if _, err := ioutil.ReadAll(r0); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	r0.Close()
+
+	// fail part way through a queued block
+	buf := &bytes.Buffer{}
+	n, err := r1.WriteTo(io.MultiWriter(buf, &failWriter{105, errors.New("testing")}))
+	if err == nil || n != 105 {
+		t.Fatalf("unexpected value: %d %v", n, err)
+	}
+	buf.Truncate(105)
+	n, err = r1.WriteTo(buf)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if n != int64(len(LONG_GREEK)-105) || buf.String() != LONG_GREEK {
+		t.Fatalf("unexpected value")
+	}
+
+}
+
+func TestWriteBatchPacket(t *testing.T) {
+
+	msgs := []string{"hello", "a", "a much longer message", "bye"}
+	mr := NewMultiplexReader(&datagrams{append([]string{}, msgs...)})
+	mr.SetPacketMode(64)
+	r0 := mr.NewReader()
+	r1 := mr.NewReader()
+	if _, err := ioutil.ReadAll(r0); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	r0.Close()
+
+	w := &writes{}
+	if _, err := r1.WriteTo(w); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if strings.Join(w.ws, "|") != strings.Join(msgs, "|") {
+		t.Fatalf("unexpected value: %q", w.ws)
+	}
+
+}